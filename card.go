@@ -1,38 +1,55 @@
 package stripe
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"net/url"
-	"strconv"
+	"strings"
 )
 
 // Card is a representation of a credit card, as reported by the Stripe API.
 type Card struct {
 	Type              string `json:"type"` // Card brand.
-	ExpYear           int    `json:"exp_year"`
-	CVC               string `json:"cvc"`
+	ExpYear           int    `json:"exp_year" form:"exp_year"`
+	CVC               string `json:"cvc" form:"cvc,omitempty"`
 	CVCCheck          string `json:"cvc_check"` // The result of a check: "pass", "fail", "unchecked", or nil
 	Country           string `json:"country"`   // Stripe's "best guess" at the country the card is in
-	Name              string `json:"name"`
-	AddressCountry    string `json:"address_country"` // The user-supplied country for the card's billing address
-	State             string `json:"address_state"`
-	Zip               string `json:"address_zip"`
-	AddressLine1      string `json:"address_line1"`
-	AddressLine2      string `json:"address_line2"`
+	Name              string `json:"name" form:"name,omitempty"`
+	AddressCountry    string `json:"address_country" form:"address_country,omitempty"` // The user-supplied country for the card's billing address
+	State             string `json:"address_state" form:"address_state,omitempty"`
+	Zip               string `json:"address_zip" form:"address_zip,omitempty"`
+	AddressLine1      string `json:"address_line1" form:"address_line1,omitempty"`
+	AddressLine2      string `json:"address_line2" form:"address_line2,omitempty"`
 	LastFour          string `json:"last4"`
-	Number            string `json:"number"`
+	Number            string `json:"number" form:"number"`
 	Object            string `json:"object"` // Should always be "card"
-	ExpMonth          int    `json:"exp_month"`
+	ExpMonth          int    `json:"exp_month" form:"exp_month"`
 	Fingerprint       string `json:"fingerprint"`         // This uniquely identifies the card's number
 	AddressZipCheck   string `json:"address_zip_check"`   // The result of a zip code check: "pass", "fail", "unchecked", or "nil"
 	AddressLine1Check string `json:"address_line1_check"` // The result of an address check: "pass", "fail", "unchecked", or "nil"
 	ID                string `json:"id"`
+	rawObject
 }
 
-func (card *Card) String() string {
+// UnmarshalJSON implements json.Unmarshaler.
+func (card *Card) UnmarshalJSON(data []byte) error {
+	type plainCard Card
+	return decodeRaw(data, (*plainCard)(card), &card.rawObject)
+}
+
+// MarshalJSON implements json.Marshaler. Unlike String, GoString and LogValue,
+// it doesn't mask card's number or security code.
+func (card Card) MarshalJSON() ([]byte, error) {
+	type plainCard Card
+	return encodeRaw(plainCard(card), card.rawObject)
+}
+
+// String describes card without exposing its number or security code.
+func (card Card) String() string {
 	if card.Number != "" {
-		return card.Number
+		return MaskCardNumber(card.Number)
 	}
 	if card.ID != "" {
 		return card.ID
@@ -55,49 +72,49 @@ func (card *Card) String() string {
 	return "Unknown card."
 }
 
+// GoString keeps card's number and security code out of %#v output.
+func (card Card) GoString() string {
+	type plainCard Card
+	return strings.Replace(fmt.Sprintf("%#v", plainCard(card.redacted())), "plainCard", "Card", 1)
+}
+
+// LogValue implements slog.LogValuer, keeping card's number and security code
+// out of logs.
+func (card Card) LogValue() slog.Value {
+	type plainCard Card
+	return slog.AnyValue(plainCard(card.redacted()))
+}
+
+// redacted returns a copy of card with its number and security code masked.
+func (card Card) redacted() Card {
+	card.Number = MaskCardNumber(card.Number)
+	if card.CVC != "" {
+		card.CVC = mask
+	}
+	return card
+}
+
 // ChargeValues sets *card's non-empty properties to their appropriate key in *values
 // This is useful for constructing HTTP requests from Card objects
 // This also satisfies the Chargeable interface, allowing Cards to be charged
 func (card *Card) ChargeValues(values *url.Values) error {
 	if card == nil {
-		// TODO: Throw error
-	}
-	if card.Number != "" {
-		values.Set("card[number]", card.Number)
-	} else {
-		// TODO: Throw error
-	}
-	if card.ExpMonth != 0 {
-		values.Set("card[exp_month]", strconv.Itoa(card.ExpMonth))
-	} else {
-		// TODO: Throw error
-	}
-	if card.ExpYear != 0 {
-		values.Set("card[exp_year]", strconv.Itoa(card.ExpYear))
-	} else {
-		// TODO: throw error
-	}
-	if card.CVC != "" {
-		values.Set("card[cvc]", card.CVC)
+		return invalid("card", "no card was provided")
 	}
-	if card.Name != "" {
-		values.Set("card[name]", card.Name)
-	}
-	if card.AddressLine1 != "" {
-		values.Set("card[address_line1]", card.AddressLine1)
+	if card.Number == "" {
+		return invalid("card[number]", "the card number is required")
 	}
-	if card.AddressLine2 != "" {
-		values.Set("card[address_line2]", card.AddressLine2)
+	if card.ExpMonth < 1 || card.ExpMonth > 12 {
+		return invalid("card[exp_month]", "the expiration month must be between 1 and 12")
 	}
-	if card.Zip != "" {
-		values.Set("card[address_zip]", card.Zip)
+	if card.ExpYear <= 0 {
+		return invalid("card[exp_year]", "the expiration year is required")
 	}
-	if card.State != "" {
-		values.Set("card[address_state]", card.State)
+	err := encodeForm("card", card, values)
+	if err != nil {
+		return err
 	}
-	if card.AddressCountry != "" {
-		values.Set("card[address_country]", card.AddressCountry)
-	} else if card.Country != "" {
+	if card.AddressCountry == "" && card.Country != "" {
 		values.Set("card[address_country]", card.Country)
 	}
 	return nil
@@ -114,30 +131,64 @@ type Token struct {
 	ID       string    `json:"id"`
 	Card     *Card     `json:"card"`
 	Error    *RawError `json:"error"`
+	rawObject
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (token *Token) UnmarshalJSON(data []byte) error {
+	type plainToken Token
+	return decodeRaw(data, (*plainToken)(token), &token.rawObject)
+}
+
+// MarshalJSON implements json.Marshaler.
+func (token Token) MarshalJSON() ([]byte, error) {
+	type plainToken Token
+	return encodeRaw(plainToken(token), token.rawObject)
 }
 
 func (token *Token) String() string {
 	return fmt.Sprintf("%s (%s)", token.ID, token.Card)
 }
 
+// LogValue implements slog.LogValuer, keeping the number and security code of
+// token's card out of logs.
+func (token Token) LogValue() slog.Value {
+	type plainToken Token
+	return slog.AnyValue(plainToken(token.redacted()))
+}
+
+// redacted returns a copy of token whose card has its number and security code
+// masked.
+func (token Token) redacted() Token {
+	if token.Card != nil {
+		card := token.Card.redacted()
+		token.Card = &card
+	}
+	return token
+}
+
 // ChargeValues sets *token's ID to the appropriate key in *values.
 // This is handy for constructing HTTP requests from Tokens.
 // This also satisfies the Chargeable interface, allowing tokens to be charged.
 func (token *Token) ChargeValues(values *url.Values) error {
 	if token == nil {
-		// TODO: throw an error
+		return invalid("card", "no token was provided")
 	}
-	if token.ID != "" {
-		values.Set("card", token.ID)
-	} else {
-		// TODO: Throw an error
+	if token.ID == "" {
+		return invalid("card", "the token has no ID")
 	}
+	values.Set("card", token.ID)
 	return nil
 }
 
 // GetToken retrieves the Token with an ID of id from Stripe
 func (stripe *Stripe) GetToken(id string) (resp *Token, err error) {
-	r, err := stripe.request("GET", "tokens/"+id, "")
+	return stripe.GetTokenCtx(context.Background(), id)
+}
+
+// GetTokenCtx is like GetToken, but uses ctx for the request to Stripe.
+func (stripe *Stripe) GetTokenCtx(ctx context.Context, id string) (resp *Token, err error) {
+	r, err := stripe.request(ctx, "GET", "tokens/"+id, "")
 	if err != nil {
 		return nil, err
 	}
@@ -146,23 +197,25 @@ func (stripe *Stripe) GetToken(id string) (resp *Token, err error) {
 		return nil, err
 	}
 	if resp.Error != nil {
-		//TODO: Throw an error
+		return nil, resp.Error.typed(200)
 	}
-	return resp, err
+	return resp, nil
 }
 
 // Create token swaps credit card details for a Token on Stripe's servers
 func (stripe *Stripe) CreateToken(card *Card) (resp *Token, err error) {
+	return stripe.CreateTokenCtx(context.Background(), card)
+}
+
+// CreateTokenCtx is like CreateToken, but uses ctx for the request to Stripe.
+func (stripe *Stripe) CreateTokenCtx(ctx context.Context, card *Card) (resp *Token, err error) {
 	values := make(url.Values)
-	if card == nil {
-		// TODO: throw an error
-	}
 	err = card.ChargeValues(&values)
 	if err != nil {
 		return nil, err
 	}
 	params := values.Encode()
-	r, err := stripe.request("POST", "tokens", params)
+	r, err := stripe.request(ctx, "POST", "tokens", params)
 	if err != nil {
 		return nil, err
 	}
@@ -171,7 +224,7 @@ func (stripe *Stripe) CreateToken(card *Card) (resp *Token, err error) {
 		return nil, err
 	}
 	if resp.Error != nil {
-		//TODO: Throw an error
+		return nil, resp.Error.typed(200)
 	}
 	return resp, nil
 }
@@ -1,11 +1,13 @@
 package stripe
 
 import (
-	"io/ioutil"
-	"strconv"
+	"errors"
+	"net/url"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/secondbit/stripe/stripetest"
 )
 
 var (
@@ -33,16 +35,20 @@ var (
 		State:          "",                   // TODO: Come up with an invalid value
 		AddressCountry: "Spain",              // TODO: come up with an invalid value
 	}
-	key, err = ioutil.ReadFile("key")
 )
 
+// emulate returns a *Stripe that talks to a new stripetest.Server, which is
+// closed when the test finishes.
+func emulate(t *testing.T) (*Stripe, *stripetest.Server) {
+	t.Helper()
+	server := stripetest.NewServer()
+	t.Cleanup(server.Close)
+	return New("sk_test_key", WithBaseURL(server.BaseURL())), server
+}
+
 func TestCreateCardToken(t *testing.T) {
-	if err != nil {
-		t.Fatalf("err = %v, want %v", err, nil)
-	}
-	API := New(string(key))
-	var token *Token
-	token, err = API.CreateToken(VALID)
+	API, _ := emulate(t)
+	token, err := API.CreateToken(VALID)
 	if err != nil {
 		t.Fatalf("err = %v, want %v", err, nil)
 	}
@@ -54,84 +60,368 @@ func TestCreateCardToken(t *testing.T) {
 		t.Errorf("ExpYear is %v, expected %v", token.Card.ExpYear, VALID.ExpYear)
 	}
 	if token.Card.ExpMonth != VALID.ExpMonth {
-		t.Errorf("ExpMonth is %v, expected %v", strconv.Itoa(token.Card.ExpMonth), VALID.ExpMonth)
+		t.Errorf("ExpMonth is %v, expected %v", token.Card.ExpMonth, VALID.ExpMonth)
 	}
 	if !strings.HasSuffix(VALID.Number, token.Card.LastFour) {
-		t.Errorf("token.Card.LastFour is %v, expected %v%v%v%v", token.Card.LastFour, VALID.Number[len(VALID.Number)-1], VALID.Number[len(VALID.Number)-2], VALID.Number[len(VALID.Number)-3], VALID.Number[len(VALID.Number)-4])
+		t.Errorf("token.Card.LastFour is %v, expected %v", token.Card.LastFour, VALID.Number[len(VALID.Number)-4:])
+	}
+	if token.Card.Number != "" {
+		t.Errorf("token.Card.Number is %v, expected it to be withheld", token.Card.Number)
+	}
+	if token.Card.Type != "Visa" {
+		t.Errorf("token.Card.Type is %v, expected %v", token.Card.Type, "Visa")
 	}
 	if token.Card.Name != VALID.Name {
 		t.Errorf("token.Card.Name is %v, expected %v", token.Card.Name, VALID.Name)
 	}
 	if token.Card.AddressCountry != VALID.AddressCountry {
-		t.Error("token.Card.AddressCountry is %v, expected %v", token.Card.AddressCountry, VALID.AddressCountry)
+		t.Errorf("token.Card.AddressCountry is %v, expected %v", token.Card.AddressCountry, VALID.AddressCountry)
 	}
 	if token.Card.AddressLine1 != VALID.AddressLine1 {
-		t.Error("token.Card.AddressLine1 is %v, expected %v", token.Card.AddressLine1, VALID.AddressLine1)
+		t.Errorf("token.Card.AddressLine1 is %v, expected %v", token.Card.AddressLine1, VALID.AddressLine1)
 	}
 	if token.Card.AddressLine2 != VALID.AddressLine2 {
-		t.Error("token.Card.AddressLine2 is %v, expected %v", token.Card.AddressLine2, VALID.AddressLine2)
+		t.Errorf("token.Card.AddressLine2 is %v, expected %v", token.Card.AddressLine2, VALID.AddressLine2)
 	}
 	if token.Card.Zip != VALID.Zip {
-		t.Error("token.Card.Zip is %v, expected %v", token.Card.Zip, VALID.Zip)
+		t.Errorf("token.Card.Zip is %v, expected %v", token.Card.Zip, VALID.Zip)
 	}
 	if token.Card.State != VALID.State {
-		t.Error("token.Card.State is %v, expected %v", token.Card.State, VALID.State)
+		t.Errorf("token.Card.State is %v, expected %v", token.Card.State, VALID.State)
 	}
 }
 
-// TODO: Test with every permutation of values
+func TestCreateInvalidCardToken(t *testing.T) {
+	API, _ := emulate(t)
+	_, err := API.CreateToken(INVALID)
+	var cardErr *CardError
+	if !errors.As(err, &cardErr) {
+		t.Fatalf("err = %v, want a *CardError", err)
+	}
+	if cardErr.Code != "incorrect_number" {
+		t.Errorf("Code is %v, expected %v", cardErr.Code, "incorrect_number")
+	}
+}
+
+func TestGetCardToken(t *testing.T) {
+	API, _ := emulate(t)
+	token, err := API.CreateToken(VALID)
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	token2, err := API.GetToken(token.ID)
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	if token2.Used {
+		t.Errorf("token2.Used is %v, expected %v", token2.Used, false)
+	}
+	if token2.Card.LastFour != token.Card.LastFour {
+		t.Errorf("token2.Card.LastFour is %v, expected %v", token2.Card.LastFour, token.Card.LastFour)
+	}
+	if token2.Card.Fingerprint != token.Card.Fingerprint {
+		t.Errorf("token2.Card.Fingerprint is %v, expected %v", token2.Card.Fingerprint, token.Card.Fingerprint)
+	}
+	_, err = API.GetToken("tok_missing")
+	var notFound *NotFoundError
+	if !errors.As(err, &notFound) {
+		t.Errorf("err = %v, want a *NotFoundError", err)
+	}
+}
+
+func TestChargeToken(t *testing.T) {
+	API, _ := emulate(t)
+	token, err := API.CreateToken(VALID)
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	charge, err := API.CreateCharge(token, 2000, "usd", "a test charge")
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	if !charge.Paid {
+		t.Errorf("Paid is %v, expected %v", charge.Paid, true)
+	}
+	if charge.Card == nil || charge.Card.LastFour != "4242" {
+		t.Errorf("Card is %v, expected a card ending in 4242", charge.Card)
+	}
+	_, err = API.CreateCharge(token, 2000, "usd", "")
+	var invalidErr *InvalidRequestError
+	if !errors.As(err, &invalidErr) {
+		t.Errorf("err = %v, want an *InvalidRequestError for a reused token", err)
+	}
+}
+
+func TestRefundCharge(t *testing.T) {
+	API, _ := emulate(t)
+	charge, err := API.CreateCharge(VALID, 2000, "usd", "")
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	charge, err = API.RefundCharge(charge.ID, 500)
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	if charge.Refunded {
+		t.Errorf("Refunded is %v, expected %v after a partial refund", charge.Refunded, false)
+	}
+	if charge.AmountRefunded != 500 {
+		t.Errorf("AmountRefunded is %v, expected %v", charge.AmountRefunded, 500)
+	}
+	charge, err = API.RefundCharge(charge.ID, -1)
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	if !charge.Refunded {
+		t.Errorf("Refunded is %v, expected %v", charge.Refunded, true)
+	}
+	charges, err := API.ListCharges(-1, -1, "")
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	if len(charges) != 1 || charges[0].ID != charge.ID {
+		t.Errorf("charges is %v, expected only %v", charges, charge.ID)
+	}
+}
+
+func TestAuthorizeAndCaptureCharge(t *testing.T) {
+	API, _ := emulate(t)
+	charge, err := API.AuthorizeCharge(VALID, 2000, "usd", "a hold")
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	if !charge.Paid || charge.Captured {
+		t.Errorf("Paid is %v and Captured is %v, expected %v and %v", charge.Paid, charge.Captured, true, false)
+	}
+	_, err = API.RefundCharge(charge.ID, 500)
+	var invalidErr *InvalidRequestError
+	if !errors.As(err, &invalidErr) {
+		t.Errorf("err = %v, want an *InvalidRequestError for a partial refund of a hold", err)
+	}
+	charge, err = API.CaptureCharge(charge.ID, 1500)
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	if !charge.Captured {
+		t.Errorf("Captured is %v, expected %v", charge.Captured, true)
+	}
+	if charge.AmountRefunded != 500 {
+		t.Errorf("AmountRefunded is %v, expected %v", charge.AmountRefunded, 500)
+	}
+	if charge.Refunded {
+		t.Errorf("Refunded is %v, expected %v after a partial capture", charge.Refunded, false)
+	}
+	if charge.Refunds.Count != 1 || charge.Refunds.Data[0].Amount != 500 {
+		t.Errorf("Refunds is %+v, expected a Refund of the %v not captured", charge.Refunds, 500)
+	}
+	_, err = API.CaptureCharge(charge.ID, -1)
+	if !errors.As(err, &invalidErr) {
+		t.Errorf("err = %v, want an *InvalidRequestError for a captured charge", err)
+	}
+
+	charge, err = API.AuthorizeCharge(VALID, 2000, "usd", "")
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	_, err = API.CaptureCharge(charge.ID, 2500)
+	if !errors.As(err, &invalidErr) {
+		t.Errorf("err = %v, want an *InvalidRequestError for capturing more than was authorized", err)
+	}
+	charge, err = API.CaptureCharge(charge.ID, -1)
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	if !charge.Captured || charge.AmountRefunded != 0 {
+		t.Errorf("Captured is %v and AmountRefunded is %v, expected %v and %v", charge.Captured, charge.AmountRefunded, true, 0)
+	}
+	if charge.Fee == 0 {
+		t.Errorf("Fee is %v, expected Stripe's fee once captured", charge.Fee)
+	}
+}
+
+func TestChargeHoldExpires(t *testing.T) {
+	API, server := emulate(t)
+	start := time.Now()
+	server.Now = func() time.Time { return start }
+	charge, err := API.AuthorizeCharge(VALID, 2000, "usd", "")
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	server.Now = func() time.Time { return start.Add(stripetest.HoldDuration - time.Minute) }
+	charge, err = API.GetCharge(charge.ID)
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	if charge.Refunded {
+		t.Errorf("Refunded is %v, expected %v before the hold expires", charge.Refunded, false)
+	}
+	server.Now = func() time.Time { return start.Add(stripetest.HoldDuration) }
+	charge, err = API.GetCharge(charge.ID)
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	if !charge.Refunded || charge.AmountRefunded != 2000 || charge.Captured {
+		t.Errorf("charge is %+v, expected an uncaptured charge refunded in full", charge)
+	}
+	_, err = API.CaptureCharge(charge.ID, -1)
+	var invalidErr *InvalidRequestError
+	if !errors.As(err, &invalidErr) {
+		t.Errorf("err = %v, want an *InvalidRequestError for an expired hold", err)
+	}
+	events, err := API.ListEventsFiltered(&EventFilter{Type: EventChargeExpired}, -1, -1)
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	if len(events) != 1 || events[0].Data.Object.(*Charge).ID != charge.ID {
+		t.Errorf("events is %v, expected a %v event for %v", events, EventChargeExpired, charge.ID)
+	}
+}
+
+func TestCreateChargeWithParams(t *testing.T) {
+	API, _ := emulate(t)
+	capture := false
+	params := &ChargeParams{
+		Amount:              2000,
+		Currency:            "usd",
+		Source:              VALID,
+		Description:         "a test charge",
+		StatementDescriptor: "OSO PELUCHE",
+		ReceiptEmail:        "oso@example.com",
+		Metadata:            map[string]string{"order": "1234"},
+		Shipping: &Shipping{
+			Name:    "Oso de Peluche",
+			Address: Address{Line1: "123 Awesome Street", City: "San Francisco", PostalCode: "94107", Country: "US"},
+		},
+		ApplicationFee: 100,
+		Capture:        &capture,
+	}
+	charge, err := API.CreateChargeWithParams(params)
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	if charge.Amount != 2000 || charge.Description != params.Description || charge.Captured {
+		t.Errorf("charge is %+v, expected an uncaptured charge of %v", charge, 2000)
+	}
+	if charge.StatementDescriptor != params.StatementDescriptor {
+		t.Errorf("StatementDescriptor is %v, expected %v", charge.StatementDescriptor, params.StatementDescriptor)
+	}
+	if charge.ReceiptEmail != params.ReceiptEmail {
+		t.Errorf("ReceiptEmail is %v, expected %v", charge.ReceiptEmail, params.ReceiptEmail)
+	}
+	if charge.Metadata["order"] != "1234" {
+		t.Errorf("Metadata is %v, expected %v", charge.Metadata, params.Metadata)
+	}
+	if charge.Shipping == nil || *charge.Shipping != *params.Shipping {
+		t.Errorf("Shipping is %+v, expected %+v", charge.Shipping, params.Shipping)
+	}
 
-// TODO: Update this test.
-
-/*func TestGetCardToken(t *testing.T) {
-        if err != nil {
-                t.Fatalf("err = %v, want %v", err, nil)
-        }
-        API := New(string(key))
-        var token, token2 *CardToken
-        token, err = API.CreateCardTokenWithAll(VALID.Number, VALID.ExpMonth, VALID.ExpYear, VALID.CVC, VALID.Name, VALID.Address1, VALID.Address2, VALID.Zip, VALID.State, VALID.Country)
-        if err != nil {
-                t.Fatalf("err = %v, want %v", err, nil)
-        }
-        if token == nil {
-                t.Fatalf("token is nil, should be set")
-        }
-        if token.ID == "" {
-                t.Fatalf("token.ID not set")
-        }
-        token2, err = API.GetCardToken(token.ID)
-        if err != nil {
-                t.Fatalf("err = %v, want %v", err, nil)
-        }
-        if token2 == nil {
-                t.Fatalf("token2 is nil, should be set")
-        }
-        if token.Card.ExpYear != token2.Card.ExpYear {
-                t.Errorf("ExpYear is %v, expected %v", token2.Card.ExpYear, token.Card.ExpYear)
-        }
-        if token.Card.ExpMonth != token2.Card.ExpMonth {
-                t.Errorf("ExpMonth is %v, expected %v", token2.Card.ExpMonth, token.Card.ExpMonth)
-        }
-        if token.Card.LastFour != token2.Card.LastFour {
-                t.Errorf("token2.Card.LastFour is %v, expected %v", token2.Card.LastFour, token.Card.LastFour)
-        }
-        if token.Card.Name != token2.Card.Name {
-                t.Errorf("token2.Card.Name is %v, expected %v", token2.Card.Name, token.Card.Name)
-        }
-        if token.Card.AddressCountry != token2.Card.AddressCountry {
-                t.Error("token2.Card.AddressCountry is %v, expected %v", token2.Card.AddressCountry, token.Card.Country)
-        }
-        if token.Card.Address1 != token2.Card.Address1 {
-                t.Error("token2.Card.Address1 is %v, expected %v", token2.Card.Address1, token.Card.Address1)
-        }
-        if token.Card.Address2 != token2.Card.Address2 {
-                t.Error("token2.Card.Address2 is %v, expected %v", token2.Card.Address2, token.Card.Address2)
-        }
-        if token.Card.Zip != token2.Card.Zip {
-                t.Error("token2.Card.Zip is %v, expected %v", token2.Card.Zip, token.Card.Zip)
-        }
-        if token.Card.State != token2.Card.State {
-                t.Error("token2.Card.State is %v, expected %v", token2.Card.State, token.Card.State)
-        }
-
-}*/
+	customer, err := API.CreateCustomer(&Customer{}, VALID, "", "", -1)
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	charge, err = API.CreateChargeWithParams(&ChargeParams{Amount: 500, Currency: "usd", Source: customer})
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	if charge.Customer != customer.ID || !charge.Captured {
+		t.Errorf("charge is %+v, expected a captured charge to %v", charge, customer.ID)
+	}
+}
+
+func TestChargeParamsValidation(t *testing.T) {
+	tests := []struct {
+		params ChargeParams
+		field  string
+	}{
+		{ChargeParams{Amount: 0, Currency: "usd", Source: VALID}, "amount"},
+		{ChargeParams{Amount: 500, Currency: "dollars", Source: VALID}, "currency"},
+		{ChargeParams{Amount: 500, Currency: "usd"}, "source"},
+		{ChargeParams{Amount: 500, Currency: "usd", Source: VALID, StatementDescriptor: "A VERY LONG STATEMENT DESCRIPTOR"}, "statement_descriptor"},
+		{ChargeParams{Amount: 500, Currency: "usd", Source: VALID, StatementDescriptor: "<OSO>"}, "statement_descriptor"},
+		{ChargeParams{Amount: 500, Currency: "usd", Source: VALID, ReceiptEmail: "oso"}, "receipt_email"},
+		{ChargeParams{Amount: 500, Currency: "usd", Source: VALID, ApplicationFee: 600}, "application_fee"},
+		{ChargeParams{Amount: 500, Currency: "usd", Source: VALID, Shipping: &Shipping{Name: "Oso"}}, "shipping[address][line1]"},
+		{ChargeParams{Amount: 500, Currency: "usd", Source: &Card{}}, "card[number]"},
+	}
+	for _, test := range tests {
+		values := make(url.Values)
+		err := test.params.Values(&values)
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) {
+			t.Errorf("err = %v, want a *ValidationError for %v", err, test.field)
+			continue
+		}
+		if validationErr.Field != test.field {
+			t.Errorf("Field is %v, expected %v", validationErr.Field, test.field)
+		}
+	}
+}
+
+func TestDeclinedCards(t *testing.T) {
+	API, _ := emulate(t)
+	tests := []struct {
+		number, code, declineCode string
+	}{
+		{stripetest.CardDeclined, "card_declined", "generic_decline"},
+		{stripetest.CardInsufficientFunds, "card_declined", "insufficient_funds"},
+		{stripetest.CardIncorrectCVC, "incorrect_cvc", ""},
+		{stripetest.CardExpired, "expired_card", ""},
+		{stripetest.CardProcessingError, "processing_error", ""},
+		{stripetest.CardIncorrectNumber, "incorrect_number", ""},
+	}
+	for _, test := range tests {
+		card := *VALID
+		card.Number = test.number
+		_, err := API.CreateCharge(&card, 2000, "usd", "")
+		var cardErr *CardError
+		if !errors.As(err, &cardErr) {
+			t.Errorf("%v: err = %v, want a *CardError", test.number, err)
+			continue
+		}
+		if cardErr.Code != test.code {
+			t.Errorf("%v: Code is %v, expected %v", test.number, cardErr.Code, test.code)
+		}
+		if cardErr.DeclineCode != test.declineCode {
+			t.Errorf("%v: DeclineCode is %v, expected %v", test.number, cardErr.DeclineCode, test.declineCode)
+		}
+		if cardErr.Details() == "" {
+			t.Errorf("%v: Details is empty for Code %v", test.number, cardErr.Code)
+		}
+		if test.code == "incorrect_number" {
+			continue
+		}
+		charge, err := API.GetCharge(cardErr.ChargeID)
+		if err != nil {
+			t.Fatalf("err = %v, want %v", err, nil)
+		}
+		if charge.Paid {
+			t.Errorf("%v: Paid is %v, expected %v", test.number, charge.Paid, false)
+		}
+	}
+}
+
+func TestCardDeclinedAfterAttaching(t *testing.T) {
+	API, _ := emulate(t)
+	declined := *VALID
+	declined.Number = stripetest.CardDeclined
+	_, err := API.CreateCustomer(&Customer{}, &declined, "", "", -1)
+	var cardErr *CardError
+	if !errors.As(err, &cardErr) {
+		t.Fatalf("err = %v, want a *CardError", err)
+	}
+	card := *VALID
+	card.Number = stripetest.CardDeclinedAfterAttaching
+	customer, err := API.CreateCustomer(&Customer{}, &card, "", "", -1)
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	_, err = API.CreateCharge(customer, 2000, "usd", "")
+	if !errors.As(err, &cardErr) {
+		t.Fatalf("err = %v, want a *CardError", err)
+	}
+	if cardErr.Code != "card_declined" {
+		t.Errorf("Code is %v, expected %v", cardErr.Code, "card_declined")
+	}
+}
+
+// TODO: Test with every permutation of values
@@ -0,0 +1,74 @@
+package stripe
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/secondbit/stripe/stripetest"
+)
+
+func TestCassetteRecordAndReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "charge.json")
+	server := stripetest.NewServer()
+	recorder, err := stripetest.LoadCassette(path, stripetest.Record)
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	API := New("sk_test_secretkey", WithBaseURL(server.BaseURL()), WithHTTPClient(recorder.Client()))
+	token, err := API.CreateToken(VALID)
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	recorded, err := API.CreateCharge(token, 2000, "usd", "")
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	server.Close()
+	if err := recorder.Save(); err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	for _, secret := range []string{VALID.Number, "secretkey", "cvc%5D=123"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("cassette contains %q, expected it to be scrubbed", secret)
+		}
+	}
+
+	player, err := stripetest.LoadCassette(path, stripetest.Replay)
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	API = New("sk_test_otherkey", WithBaseURL("http://stripe.invalid/v1/"), WithHTTPClient(player.Client()))
+	token, err = API.CreateToken(VALID)
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	replayed, err := API.CreateCharge(token, 2000, "usd", "")
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	if replayed.ID != recorded.ID {
+		t.Errorf("ID is %v, expected %v", replayed.ID, recorded.ID)
+	}
+	_, err = API.CreateCharge(token, 2000, "usd", "")
+	var unmatched *stripetest.UnmatchedRequestError
+	if !errors.As(err, &unmatched) {
+		t.Fatalf("err = %v, want an *UnmatchedRequestError", err)
+	}
+	if unmatched.Request.Method != "POST" || unmatched.Request.URL != "/v1/charges" {
+		t.Errorf("Request is %+v, expected POST /v1/charges", unmatched.Request)
+	}
+}
+
+func TestCassetteReplayMissingFile(t *testing.T) {
+	_, err := stripetest.LoadCassette(filepath.Join(t.TempDir(), "missing.json"), stripetest.Replay)
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("err = %v, want %v", err, os.ErrNotExist)
+	}
+}
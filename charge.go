@@ -1,51 +1,244 @@
 package stripe
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"log/slog"
+	"net/mail"
 	"net/url"
 	"strconv"
+	"strings"
+	"unicode/utf8"
 )
 
 // Chargeable is an interface to expose items that can be charged.
 // The Stripe API uses credit cards, tokens, and customers interchangeably for most operations
-// dealing with money; rather than writing three versions of all these operations, Chargeable 
+// dealing with money; rather than writing three versions of all these operations, Chargeable
 // allows a single version to work for any of the types that satisfy it.
 type Chargeable interface {
 	ChargeValues(values *url.Values) error
 }
 
 type Charge struct {
-	Amount      int       `json:"amount"`
-	Currency    string    `json:"currency"`
-	Card        *Card     `json:"card"`
-	Customer    string    `json:"customer"` // The Customer's ID
-	Description string    `json:"description"`
-	Created     int       `json:"created"`
-	Fee         int       `json:"fee"`
-	ID          string    `json:"id"`
-	LiveMode    bool      `json:"livemode"`
-	Object      string    `json:"object"` // Should always be "charge"
-	Paid        bool      `json:"paid"`
-	Refunded    bool      `json:"refunded"`
-	Error       *RawError `json:"error"`
+	Amount              int               `json:"amount"`
+	Currency            string            `json:"currency"`
+	Card                *Card             `json:"card"`
+	Customer            string            `json:"customer"` // The Customer's ID
+	Description         string            `json:"description"`
+	StatementDescriptor string            `json:"statement_descriptor"` // Shown on the customer's card statement
+	ReceiptEmail        string            `json:"receipt_email"`        // Where the receipt for the Charge is sent
+	Shipping            *Shipping         `json:"shipping"`
+	Created             int               `json:"created"`
+	Fee                 int               `json:"fee"`
+	ID                  string            `json:"id"`
+	LiveMode            bool              `json:"livemode"`
+	Object              string            `json:"object"` // Should always be "charge"
+	Paid                bool              `json:"paid"`
+	Captured            bool              `json:"captured"` // False while the Charge is only an authorization
+	Refunded            bool              `json:"refunded"` // Whether the whole Charge has been refunded
+	AmountRefunded      int               `json:"amount_refunded"`
+	Refunds             RefundList        `json:"refunds"`
+	Metadata            map[string]string `json:"metadata"`
+	Error               *RawError         `json:"error"`
+	rawObject
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (charge *Charge) UnmarshalJSON(data []byte) error {
+	type plainCharge Charge
+	return decodeRaw(data, (*plainCharge)(charge), &charge.rawObject)
+}
+
+// MarshalJSON implements json.Marshaler.
+func (charge Charge) MarshalJSON() ([]byte, error) {
+	type plainCharge Charge
+	return encodeRaw(plainCharge(charge), charge.rawObject)
+}
+
+// Shipping is where the goods paid for by a Charge are shipped to.
+type Shipping struct {
+	Name           string  `json:"name" form:"name"`
+	Phone          string  `json:"phone" form:"phone,omitempty"`
+	Address        Address `json:"address" form:"address"`
+	Carrier        string  `json:"carrier" form:"carrier,omitempty"`
+	TrackingNumber string  `json:"tracking_number" form:"tracking_number,omitempty"`
+}
+
+// Address is a postal address.
+type Address struct {
+	Line1      string `json:"line1" form:"line1"`
+	Line2      string `json:"line2" form:"line2,omitempty"`
+	City       string `json:"city" form:"city,omitempty"`
+	State      string `json:"state" form:"state,omitempty"`
+	PostalCode string `json:"postal_code" form:"postal_code,omitempty"`
+	Country    string `json:"country" form:"country,omitempty"`
+}
+
+// MaxStatementDescriptorLength is the longest a Charge's statement descriptor can be.
+const MaxStatementDescriptorLength = 22
+
+// ChargeParams holds everything that can be set when creating a Charge with
+// CreateChargeWithParams. Amount, Currency and Source are required.
+type ChargeParams struct {
+	Amount   int    `form:"amount"` // In the currency's smallest unit, e.g. cents
+	Currency string `form:"currency"`
+	// Source is what is charged: a *Card, a *Token, or a *Customer. Values
+	// encodes it with its ChargeValues method, under keys such as card or
+	// customer, so it has no form key of its own.
+	Source Chargeable `form:"-"`
+
+	Description string `form:"description,omitempty"`
+	// StatementDescriptor is shown on the customer's card statement, in place
+	// of the account's default. It's at most 22 characters, none of them <, >,
+	// ", or '.
+	StatementDescriptor string `form:"statement_descriptor,omitempty"`
+	// ReceiptEmail is sent a receipt for the Charge.
+	ReceiptEmail string            `form:"receipt_email,omitempty"`
+	Metadata     map[string]string `form:"metadata,omitempty"`
+	Shipping     *Shipping         `form:"shipping,omitempty"`
+	// ApplicationFee is taken from the Charge for the platform making it on
+	// behalf of a connected account.
+	ApplicationFee int `form:"application_fee,omitempty"`
+	// Capture, if set to false, only authorizes the Charge, as AuthorizeCharge
+	// does. The Charge is captured at once if it's nil.
+	Capture *bool `form:"capture,omitempty"`
+
+	// IdempotencyKey, if set, is sent as the request's Idempotency-Key, as with
+	// ContextWithIdempotencyKey.
+	IdempotencyKey string `form:"-"`
+}
+
+// Values validates *params, and sets its non-empty properties to their
+// appropriate keys in *values.
+func (params *ChargeParams) Values(values *url.Values) error {
+	if params == nil {
+		return invalid("charge", "no charge was provided")
+	}
+	if params.Amount <= 0 {
+		return invalid("amount", "must be positive")
+	}
+	if !validCurrency(params.Currency) {
+		return invalid("currency", "must be a three-letter ISO currency code")
+	}
+	if params.Source == nil {
+		return invalid("source", "nothing to charge was provided")
+	}
+	if utf8.RuneCountInString(params.StatementDescriptor) > MaxStatementDescriptorLength {
+		return invalid("statement_descriptor", fmt.Sprintf("must be at most %d characters", MaxStatementDescriptorLength))
+	}
+	if strings.ContainsAny(params.StatementDescriptor, `<>"'`) {
+		return invalid("statement_descriptor", `must not contain <, >, ", or '`)
+	}
+	if params.ReceiptEmail != "" {
+		if _, err := mail.ParseAddress(params.ReceiptEmail); err != nil {
+			return invalid("receipt_email", "must be an email address")
+		}
+	}
+	if params.ApplicationFee < 0 || params.ApplicationFee > params.Amount {
+		return invalid("application_fee", "must be between 0 and the amount charged")
+	}
+	if params.Shipping != nil {
+		if params.Shipping.Name == "" {
+			return invalid("shipping[name]", "is required")
+		}
+		if params.Shipping.Address.Line1 == "" {
+			return invalid("shipping[address][line1]", "is required")
+		}
+	}
+	err := validMetadata(params.Metadata)
+	if err != nil {
+		return err
+	}
+	err = params.Source.ChargeValues(values)
+	if err != nil {
+		return err
+	}
+	return encodeForm("", params, values)
+}
+
+// LogValue implements slog.LogValuer, keeping the number and security code of
+// a card being charged out of logs.
+func (params ChargeParams) LogValue() slog.Value {
+	type plainChargeParams ChargeParams
+	switch source := params.Source.(type) {
+	case *Card:
+		if source != nil {
+			card := source.redacted()
+			params.Source = &card
+		}
+	case *Token:
+		if source != nil {
+			token := source.redacted()
+			params.Source = &token
+		}
+	}
+	return slog.AnyValue(plainChargeParams(params))
+}
+
+// CreateChargeWithParams is like CreateCharge, but creates the Charge described
+// by params, which can set everything Stripe allows a new Charge to have.
+func (stripe *Stripe) CreateChargeWithParams(params *ChargeParams) (resp *Charge, err error) {
+	return stripe.CreateChargeWithParamsCtx(context.Background(), params)
+}
+
+// CreateChargeWithParamsCtx is like CreateChargeWithParams, but uses ctx for the request to Stripe.
+func (stripe *Stripe) CreateChargeWithParamsCtx(ctx context.Context, params *ChargeParams) (resp *Charge, err error) {
+	values := make(url.Values)
+	err = params.Values(&values)
+	if err != nil {
+		return nil, err
+	}
+	if params.IdempotencyKey != "" {
+		ctx = ContextWithIdempotencyKey(ctx, params.IdempotencyKey)
+	}
+	return stripe.postCharge(ctx, values)
 }
 
 // CreateCharge submits a charge object to the Stripe servers, at which point Stripe will charge the card.
-// Description is optional.
+// Description is optional. CreateChargeWithParams allows the rest of a Charge's options to be set.
 func (stripe *Stripe) CreateCharge(chargeable Chargeable, amount int, currency, description string) (resp *Charge, err error) {
+	return stripe.CreateChargeCtx(context.Background(), chargeable, amount, currency, description)
+}
+
+// CreateChargeCtx is like CreateCharge, but uses ctx for the request to Stripe.
+func (stripe *Stripe) CreateChargeCtx(ctx context.Context, chargeable Chargeable, amount int, currency, description string) (resp *Charge, err error) {
+	return stripe.createCharge(ctx, chargeable, amount, currency, description, true)
+}
+
+// AuthorizeCharge is like CreateCharge, but only places a hold on the card for
+// amount, without capturing it. The Charge is returned with Captured set to
+// false, and must be captured with CaptureCharge within seven days, after which
+// the hold expires and the Charge is refunded.
+func (stripe *Stripe) AuthorizeCharge(chargeable Chargeable, amount int, currency, description string) (resp *Charge, err error) {
+	return stripe.AuthorizeChargeCtx(context.Background(), chargeable, amount, currency, description)
+}
+
+// AuthorizeChargeCtx is like AuthorizeCharge, but uses ctx for the request to Stripe.
+func (stripe *Stripe) AuthorizeChargeCtx(ctx context.Context, chargeable Chargeable, amount int, currency, description string) (resp *Charge, err error) {
+	return stripe.createCharge(ctx, chargeable, amount, currency, description, false)
+}
+
+func (stripe *Stripe) createCharge(ctx context.Context, chargeable Chargeable, amount int, currency, description string, capture bool) (resp *Charge, err error) {
 	values := make(url.Values)
 	values.Set("amount", strconv.Itoa(amount))
 	values.Set("currency", currency)
 	if description != "" {
 		values.Set("description", description)
 	}
+	if !capture {
+		values.Set("capture", "false")
+	}
 	err = chargeable.ChargeValues(&values)
 	if err != nil {
 		return nil, err
 	}
-	data := values.Encode()
-	r, err := stripe.request("POST", "charges", data)
+	return stripe.postCharge(ctx, values)
+}
+
+func (stripe *Stripe) postCharge(ctx context.Context, values url.Values) (resp *Charge, err error) {
+	r, err := stripe.request(ctx, "POST", "charges", values.Encode())
 	if err != nil {
 		return nil, err
 	}
@@ -54,17 +247,22 @@ func (stripe *Stripe) CreateCharge(chargeable Chargeable, amount int, currency,
 		return nil, err
 	}
 	if resp.Error != nil {
-		// TODO: Throw an error
+		return nil, resp.Error.typed(200)
 	}
 	return
 }
 
 // GetCharge retrieves the details of a charge that has previously been created.
 func (stripe *Stripe) GetCharge(id string) (resp *Charge, err error) {
+	return stripe.GetChargeCtx(context.Background(), id)
+}
+
+// GetChargeCtx is like GetCharge, but uses ctx for the request to Stripe.
+func (stripe *Stripe) GetChargeCtx(ctx context.Context, id string) (resp *Charge, err error) {
 	if id == "" {
 		return nil, errors.New("No ID set.")
 	}
-	r, err := stripe.request("GET", "charges/"+id, "")
+	r, err := stripe.request(ctx, "GET", "charges/"+id, "")
 	if err != nil {
 		return nil, err
 	}
@@ -73,21 +271,59 @@ func (stripe *Stripe) GetCharge(id string) (resp *Charge, err error) {
 		return nil, err
 	}
 	if resp.Error != nil {
-		// TODO: Throw an error
+		return nil, resp.Error.typed(200)
 	}
-	return resp, err
+	return resp, nil
 }
 
 // RefundCharge refunds all or part of a charge. To refund all of a charge, pass -1
-// as the amount.
+// as the amount. CreateRefund does the same, but returns the new Refund, and
+// allows a reason and metadata to be given for it.
 func (stripe *Stripe) RefundCharge(id string, amount int) (resp *Charge, err error) {
+	return stripe.RefundChargeCtx(context.Background(), id, amount)
+}
+
+// RefundChargeCtx is like RefundCharge, but uses ctx for the request to Stripe.
+func (stripe *Stripe) RefundChargeCtx(ctx context.Context, id string, amount int) (resp *Charge, err error) {
+	var body string
+	if amount >= 0 {
+		values := make(url.Values)
+		values.Set("amount", strconv.Itoa(amount))
+		body = values.Encode()
+	}
+	r, err := stripe.request(ctx, "POST", "charges/"+id+"/refund", body)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(r, &resp)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != nil {
+		return nil, resp.Error.typed(200)
+	}
+	return
+}
+
+// CaptureCharge captures all or part of a Charge created by AuthorizeCharge. To
+// capture all of it, pass -1 as the amount; whatever part of it isn't captured
+// is released, and shows in the Charge's AmountRefunded.
+func (stripe *Stripe) CaptureCharge(id string, amount int) (resp *Charge, err error) {
+	return stripe.CaptureChargeCtx(context.Background(), id, amount)
+}
+
+// CaptureChargeCtx is like CaptureCharge, but uses ctx for the request to Stripe.
+func (stripe *Stripe) CaptureChargeCtx(ctx context.Context, id string, amount int) (resp *Charge, err error) {
+	if id == "" {
+		return nil, invalid("id", "no ID was provided")
+	}
 	var body string
 	if amount >= 0 {
 		values := make(url.Values)
 		values.Set("amount", strconv.Itoa(amount))
 		body = values.Encode()
 	}
-	r, err := stripe.request("POST", "charges/"+id+"/refund", body)
+	r, err := stripe.request(ctx, "POST", "charges/"+id+"/capture", body)
 	if err != nil {
 		return nil, err
 	}
@@ -96,7 +332,7 @@ func (stripe *Stripe) RefundCharge(id string, amount int) (resp *Charge, err err
 		return nil, err
 	}
 	if resp.Error != nil {
-		// TODO: Throw an error
+		return nil, resp.Error.typed(200)
 	}
 	return
 }
@@ -106,12 +342,22 @@ func (stripe *Stripe) RefundCharge(id string, amount int) (resp *Charge, err err
 // All the arguments are optional.
 //
 // Pass -1 to count to use the Stripe default (10). Count determines the number of charges to return. The maximum is 100.
-// 
+//
 // Pass -1 to offset to use the Stripe default (0). Offset determines the number of recent charges to skip.
-// 
-// Pass anything but an empty string to customer to show only that customer's charges.
 //
+// Pass anything but an empty string to customer to show only that customer's charges.
 func (stripe *Stripe) ListCharges(count, offset int, customer string) (resp []*Charge, err error) {
+	return stripe.ListChargesCtx(context.Background(), count, offset, customer)
+}
+
+// ListChargesCtx is like ListCharges, but uses ctx for the request to Stripe.
+func (stripe *Stripe) ListChargesCtx(ctx context.Context, count, offset int, customer string) (resp []*Charge, err error) {
+	resp, _, err = stripe.listCharges(ctx, count, offset, customer)
+	return
+}
+
+// listCharges returns a page of Charges, along with the total number of Charges that match.
+func (stripe *Stripe) listCharges(ctx context.Context, count, offset int, customer string) (resp []*Charge, total int, err error) {
 	values := make(url.Values)
 	if count >= 0 {
 		values.Set("count", strconv.Itoa(count))
@@ -126,22 +372,34 @@ func (stripe *Stripe) ListCharges(count, offset int, customer string) (resp []*C
 	if params != "" {
 		params = "?" + params
 	}
-	r, err := stripe.request("GET", "charges"+params, "")
+	r, err := stripe.request(ctx, "GET", "charges"+params, "")
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	var raw struct {
-		Count int "count"
-		Data  []*Charge
-		Error *RawError "error"
+		Count int       `json:"count"`
+		Data  []*Charge `json:"data"`
+		Error *RawError `json:"error"`
 	}
 	err = json.Unmarshal(r, &raw)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	if raw.Error != nil {
-		//TODO: Throw an error
+		return nil, 0, raw.Error.typed(200)
 	}
-	resp = raw.Data
-	return
+	return raw.Data, raw.Count, nil
+}
+
+// IterCharges returns an *Iter over all of your Charges.
+// If customer is non-empty, only that customer's Charges are returned.
+func (stripe *Stripe) IterCharges(customer string) *Iter[*Charge] {
+	return stripe.IterChargesCtx(context.Background(), customer)
+}
+
+// IterChargesCtx is like IterCharges, but uses ctx for every request to Stripe.
+func (stripe *Stripe) IterChargesCtx(ctx context.Context, customer string) *Iter[*Charge] {
+	return newIter(ctx, func(ctx context.Context, count, offset int) ([]*Charge, int, error) {
+		return stripe.listCharges(ctx, count, offset, customer)
+	})
 }
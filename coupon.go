@@ -1,6 +1,7 @@
 package stripe
 
 import (
+	"context"
 	"encoding/json"
 	"net/url"
 	"strconv"
@@ -8,50 +9,55 @@ import (
 
 // Coupon represents a coupon object, according to the Stripe API.
 type Coupon struct {
-	ID               string    "id"
-	Duration         string    "duration"           // "forever", "once", "repeating"
-	DurationInMonths int       "duration_in_months" // Only useful if Duration is "repeating"
-	PercentOff       int       "percent_off"
-	MaxRedemptions   int       "max_redemptions"
-	RedeemBy         int64     "redeem_by"
-	TimesRedeemed    int       "times_redeemed"
-	Object           string    "object" // Should always be "coupon"
-	Error            *RawError "error"
+	ID               string    `json:"id" form:"id,omitempty"`
+	Duration         string    `json:"duration" form:"duration"`                               // "forever", "once", "repeating"
+	DurationInMonths int       `json:"duration_in_months" form:"duration_in_months,omitempty"` // Only useful if Duration is "repeating"
+	PercentOff       int       `json:"percent_off" form:"percent_off"`
+	MaxRedemptions   int       `json:"max_redemptions" form:"max_redemptions,omitempty"`
+	RedeemBy         int64     `json:"redeem_by" form:"redeem_by,omitempty"`
+	TimesRedeemed    int       `json:"times_redeemed"`
+	Object           string    `json:"object"` // Should always be "coupon"
+	Error            *RawError `json:"error"`
+	rawObject
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (coupon *Coupon) UnmarshalJSON(data []byte) error {
+	type plainCoupon Coupon
+	return decodeRaw(data, (*plainCoupon)(coupon), &coupon.rawObject)
+}
+
+// MarshalJSON implements json.Marshaler.
+func (coupon Coupon) MarshalJSON() ([]byte, error) {
+	type plainCoupon Coupon
+	return encodeRaw(plainCoupon(coupon), coupon.rawObject)
 }
 
 // Values assigns the applicable properties of *coupon to the appropriate keys
 // in *values. This makes constructing an HTTP request around a Coupon simpler.
 func (coupon *Coupon) Values(values *url.Values) error {
 	if coupon == nil {
-		// TODO: throw an error
-	}
-	if coupon.PercentOff < 1 {
-		// TODO: throw an error
-	} else {
-		values.Set("percent_off", strconv.Itoa(coupon.PercentOff))
+		return invalid("coupon", "no coupon was provided")
 	}
-	if coupon.Duration == "" {
-		// TODO: throw an error
-	} else {
-		values.Set("duration", coupon.Duration)
+	if coupon.PercentOff < 1 || coupon.PercentOff > 100 {
+		return invalid("percent_off", "must be between 1 and 100")
 	}
-	if coupon.Duration == "repeating" {
+	switch coupon.Duration {
+	case "forever", "once":
+		if coupon.DurationInMonths != 0 {
+			return invalid("duration_in_months", "only applies to a repeating coupon")
+		}
+	case "repeating":
 		if coupon.DurationInMonths <= 0 {
-			// TODO: throw an error
-		} else {
-			values.Set("duration_in_months", strconv.Itoa(coupon.DurationInMonths))
+			return invalid("duration_in_months", "must be positive for a repeating coupon")
 		}
+	default:
+		return invalid("duration", `must be "forever", "once", or "repeating"`)
 	}
-        if coupon.MaxRedemptions > 0 {
-		values.Set("max_redemptions", strconv.Itoa(coupon.MaxRedemptions))
-	}
-	if coupon.RedeemBy != 0 {
-		values.Set("redeem_by", strconv.FormatInt(coupon.RedeemBy, 10))
+	if coupon.MaxRedemptions < 0 {
+		return invalid("max_redemptions", "must not be negative")
 	}
-	if coupon.ID != "" {
-		values.Set("id", coupon.ID)
-	}
-	return nil
+	return encodeForm("", coupon, values)
 }
 
 // Discount represents the actual application of a Coupon to a particular Customer.
@@ -63,14 +69,35 @@ type Discount struct {
 	Customer string  `json:"customer"` // The Customer ID
 	Start    int64   `json:"start"`
 	End      int64   `json:"end"`
+	rawObject
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (discount *Discount) UnmarshalJSON(data []byte) error {
+	type plainDiscount Discount
+	return decodeRaw(data, (*plainDiscount)(discount), &discount.rawObject)
+}
+
+// MarshalJSON implements json.Marshaler.
+func (discount Discount) MarshalJSON() ([]byte, error) {
+	type plainDiscount Discount
+	return encodeRaw(plainDiscount(discount), discount.rawObject)
 }
 
 // CreateCoupon creates a coupon in Stripe.
 func (stripe *Stripe) CreateCoupon(coupon *Coupon) (resp *Coupon, err error) {
+	return stripe.CreateCouponCtx(context.Background(), coupon)
+}
+
+// CreateCouponCtx is like CreateCoupon, but uses ctx for the request to Stripe.
+func (stripe *Stripe) CreateCouponCtx(ctx context.Context, coupon *Coupon) (resp *Coupon, err error) {
 	values := make(url.Values)
-	coupon.Values(&values)
+	err = coupon.Values(&values)
+	if err != nil {
+		return nil, err
+	}
 	data := values.Encode()
-	r, err := stripe.request("POST", "coupons", data)
+	r, err := stripe.request(ctx, "POST", "coupons", data)
 	if err != nil {
 		return nil, err
 	}
@@ -79,14 +106,19 @@ func (stripe *Stripe) CreateCoupon(coupon *Coupon) (resp *Coupon, err error) {
 		return nil, err
 	}
 	if resp.Error != nil {
-		// TODO: Throw an error
+		return nil, resp.Error.typed(200)
 	}
 	return
 }
 
 // GetCoupon retrieves information about the Coupon whose ID is specified by id.
 func (stripe *Stripe) GetCoupon(id string) (resp *Coupon, err error) {
-	r, err := stripe.request("GET", "coupons/"+id, "")
+	return stripe.GetCouponCtx(context.Background(), id)
+}
+
+// GetCouponCtx is like GetCoupon, but uses ctx for the request to Stripe.
+func (stripe *Stripe) GetCouponCtx(ctx context.Context, id string) (resp *Coupon, err error) {
+	r, err := stripe.request(ctx, "GET", "coupons/"+id, "")
 	if err != nil {
 		return nil, err
 	}
@@ -95,7 +127,7 @@ func (stripe *Stripe) GetCoupon(id string) (resp *Coupon, err error) {
 		return nil, err
 	}
 	if resp.Error != nil {
-		// TODO: Throw an error
+		return nil, resp.Error.typed(200)
 	}
 	return
 }
@@ -103,23 +135,28 @@ func (stripe *Stripe) GetCoupon(id string) (resp *Coupon, err error) {
 // DeleteCoupon deletes the Coupon whose ID is specified by id.
 // Deleting a Coupon does not affect any customers who have already applied the Coupon.
 func (stripe *Stripe) DeleteCoupon(id string) (success bool, err error) {
-	r, err := stripe.request("DELETE", "coupons/"+id, "")
+	return stripe.DeleteCouponCtx(context.Background(), id)
+}
+
+// DeleteCouponCtx is like DeleteCoupon, but uses ctx for the request to Stripe.
+func (stripe *Stripe) DeleteCouponCtx(ctx context.Context, id string) (success bool, err error) {
+	r, err := stripe.request(ctx, "DELETE", "coupons/"+id, "")
 	if err != nil {
 		return false, err
 	}
 	var raw struct {
-		Success bool      "deleted"
-		ID      string    "id"
-		Error   *RawError "error"
+		Success bool      `json:"deleted"`
+		ID      string    `json:"id"`
+		Error   *RawError `json:"error"`
 	}
 	err = json.Unmarshal(r, &raw)
 	if err != nil {
 		return false, err
 	}
 	if raw.Error != nil {
-		// TODO: throw an error
+		return false, raw.Error.typed(200)
 	}
-	return raw.Success, err
+	return raw.Success, nil
 }
 
 // ListCoupons queries the server for information about all your Coupons.
@@ -127,9 +164,20 @@ func (stripe *Stripe) DeleteCoupon(id string) (success bool, err error) {
 // Both the arguments are optional.
 //
 // Pass -1 to count to use the Stripe default (10). Count determines the number of Coupons to return. The maximum is 100.
-// 
+//
 // Pass -1 to offset to use the Stripe default (0). Offset determines the number of recent Coupons to skip.
 func (stripe *Stripe) ListCoupons(count, offset int) (resp []*Coupon, err error) {
+	return stripe.ListCouponsCtx(context.Background(), count, offset)
+}
+
+// ListCouponsCtx is like ListCoupons, but uses ctx for the request to Stripe.
+func (stripe *Stripe) ListCouponsCtx(ctx context.Context, count, offset int) (resp []*Coupon, err error) {
+	resp, _, err = stripe.listCoupons(ctx, count, offset)
+	return
+}
+
+// listCoupons returns a page of Coupons, along with the total number of Coupons that match.
+func (stripe *Stripe) listCoupons(ctx context.Context, count, offset int) (resp []*Coupon, total int, err error) {
 	values := make(url.Values)
 	if count >= 0 {
 		values.Set("count", strconv.Itoa(count))
@@ -141,22 +189,33 @@ func (stripe *Stripe) ListCoupons(count, offset int) (resp []*Coupon, err error)
 	if params != "" {
 		params = "?" + params
 	}
-	r, err := stripe.request("GET", "coupons"+params, "")
+	r, err := stripe.request(ctx, "GET", "coupons"+params, "")
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	var raw struct {
-		Count int       "count"
-		Data  []*Coupon "data"
-		Error *RawError "error"
+		Count int       `json:"count"`
+		Data  []*Coupon `json:"data"`
+		Error *RawError `json:"error"`
 	}
 	err = json.Unmarshal(r, &raw)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	if raw.Error != nil {
-		// TODO: throw an error
+		return nil, 0, raw.Error.typed(200)
 	}
-	resp = raw.Data
-	return
+	return raw.Data, raw.Count, nil
+}
+
+// IterCoupons returns an *Iter over all of your Coupons.
+func (stripe *Stripe) IterCoupons() *Iter[*Coupon] {
+	return stripe.IterCouponsCtx(context.Background())
+}
+
+// IterCouponsCtx is like IterCoupons, but uses ctx for every request to Stripe.
+func (stripe *Stripe) IterCouponsCtx(ctx context.Context) *Iter[*Coupon] {
+	return newIter(ctx, func(ctx context.Context, count, offset int) ([]*Coupon, int, error) {
+		return stripe.listCoupons(ctx, count, offset)
+	})
 }
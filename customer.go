@@ -1,6 +1,7 @@
 package stripe
 
 import (
+	"context"
 	"encoding/json"
 	"net/url"
 	"strconv"
@@ -8,18 +9,32 @@ import (
 
 // Customer represents a customer, according to the Stripe API
 type Customer struct {
-	Description  string        `json:"description"`
-	Object       string        `json:"object"` // Should always be "customer"
-	LiveMode     bool          `json:"livemode"`
-	ActiveCard   *Card         `json:"active_card"`
-	Created      int64         `json:"created"`
-	ID           string        `json:"id"`
-	Balance      int64         `json:"account_balance"`
-	Error        *RawError     `json:"error"`
-	Delinquent   bool          `json:"delinquent"` // Whether the latest charge failed
-	Discount     *Discount     `json:"discount"`
-	Email        string        `json:"email"`
-	Subscription *Subscription `json:"subscription"`
+	Description  string            `json:"description" form:"description,omitempty"`
+	Object       string            `json:"object"` // Should always be "customer"
+	LiveMode     bool              `json:"livemode"`
+	ActiveCard   *Card             `json:"active_card"`
+	Created      int64             `json:"created"`
+	ID           string            `json:"id"`
+	Balance      int64             `json:"account_balance"`
+	Error        *RawError         `json:"error"`
+	Delinquent   bool              `json:"delinquent"` // Whether the latest charge failed
+	Discount     *Discount         `json:"discount"`
+	Email        string            `json:"email" form:"email,omitempty"`
+	Subscription *Subscription     `json:"subscription"`
+	Metadata     map[string]string `json:"metadata" form:"metadata,omitempty"`
+	rawObject
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (customer *Customer) UnmarshalJSON(data []byte) error {
+	type plainCustomer Customer
+	return decodeRaw(data, (*plainCustomer)(customer), &customer.rawObject)
+}
+
+// MarshalJSON implements json.Marshaler.
+func (customer Customer) MarshalJSON() ([]byte, error) {
+	type plainCustomer Customer
+	return encodeRaw(plainCustomer(customer), customer.rawObject)
 }
 
 // ChargeValues sets *customer's non-empty properties to their appropriate key in *values
@@ -27,30 +42,26 @@ type Customer struct {
 // This also satisfies the Chargeable interface, allowing Customers to be charged
 func (customer *Customer) ChargeValues(values *url.Values) error {
 	if customer == nil {
-		// TODO: Throw an error
+		return invalid("customer", "no customer was provided")
 	}
-	if customer.ActiveCard == nil {
-		// TODO: Throw an error
-	}
-	if customer.ID != "" {
-		values.Set("customer", customer.ID)
-	} else {
-		// TODO: throw an error
+	// A Customer without an ActiveCard is still sent; Stripe reports that as a
+	// *CardError with a Code of "missing".
+	if customer.ID == "" {
+		return invalid("customer", "the customer has no ID")
 	}
+	values.Set("customer", customer.ID)
 	return nil
 }
 
 func (customer *Customer) Values(values *url.Values) error {
 	if customer == nil {
-		// TODO: Throw an error
+		return invalid("customer", "no customer was provided")
 	}
-	if customer.Description != "" {
-		values.Set("description", customer.Description)
-	}
-	if customer.Email != "" {
-		values.Set("email", customer.Email)
+	err := validMetadata(customer.Metadata)
+	if err != nil {
+		return err
 	}
-	return nil
+	return encodeForm("", customer, values)
 }
 
 // CreateCustomer creates a new customer on Stripe.
@@ -67,6 +78,11 @@ func (customer *Customer) Values(values *url.Values) error {
 //
 // trial_end overrides the plan's default trial period, if not -1.
 func (stripe *Stripe) CreateCustomer(customer *Customer, chargeable Chargeable, plan, coupon string, trial_end int64) (resp *Customer, err error) {
+	return stripe.CreateCustomerCtx(context.Background(), customer, chargeable, plan, coupon, trial_end)
+}
+
+// CreateCustomerCtx is like CreateCustomer, but uses ctx for the request to Stripe.
+func (stripe *Stripe) CreateCustomerCtx(ctx context.Context, customer *Customer, chargeable Chargeable, plan, coupon string, trial_end int64) (resp *Customer, err error) {
 	values := make(url.Values)
 	err = customer.Values(&values)
 	if err != nil {
@@ -88,7 +104,7 @@ func (stripe *Stripe) CreateCustomer(customer *Customer, chargeable Chargeable,
 		values.Set("coupon", coupon)
 	}
 	data := values.Encode()
-	r, err := stripe.request("POST", "customers", data)
+	r, err := stripe.request(ctx, "POST", "customers", data)
 	if err != nil {
 		return nil, err
 	}
@@ -97,7 +113,7 @@ func (stripe *Stripe) CreateCustomer(customer *Customer, chargeable Chargeable,
 		return nil, err
 	}
 	if resp.Error != nil {
-		// TODO: Throw an error
+		return nil, resp.Error.typed(200)
 	}
 	return
 }
@@ -109,9 +125,16 @@ func (stripe *Stripe) CreateCustomer(customer *Customer, chargeable Chargeable,
 // If a Chargeable is provided, it is attached to *customer and automatically validated. Pass nil to omit the Chargeable.
 //
 // If couponID is non-empty, it is used as a Coupon that will be applied to all of *customer's recurring charges.
+//
+// Keys of *customer.Metadata are set on the Customer, leaving any others alone; keys set to "" are removed.
 func (stripe *Stripe) UpdateCustomer(customer *Customer, chargeable Chargeable, couponID string) (resp *Customer, err error) {
-	if customer.ID == "" {
-		// TODO: throw an error
+	return stripe.UpdateCustomerCtx(context.Background(), customer, chargeable, couponID)
+}
+
+// UpdateCustomerCtx is like UpdateCustomer, but uses ctx for the request to Stripe.
+func (stripe *Stripe) UpdateCustomerCtx(ctx context.Context, customer *Customer, chargeable Chargeable, couponID string) (resp *Customer, err error) {
+	if customer == nil || customer.ID == "" {
+		return nil, invalid("id", "the customer has no ID")
 	}
 	values := make(url.Values)
 	err = customer.Values(&values)
@@ -128,7 +151,7 @@ func (stripe *Stripe) UpdateCustomer(customer *Customer, chargeable Chargeable,
 		values.Set("coupon", couponID)
 	}
 	data := values.Encode()
-	r, err := stripe.request("POST", "customers/"+customer.ID, data)
+	r, err := stripe.request(ctx, "POST", "customers/"+customer.ID, data)
 	if err != nil {
 		return nil, err
 	}
@@ -137,14 +160,19 @@ func (stripe *Stripe) UpdateCustomer(customer *Customer, chargeable Chargeable,
 		return nil, err
 	}
 	if resp.Error != nil {
-		// TODO: Throw an error
+		return nil, resp.Error.typed(200)
 	}
 	return
 }
 
 // GetCustomer retrieves information on the Customer with ID of id.
 func (stripe *Stripe) GetCustomer(id string) (resp *Customer, err error) {
-	r, err := stripe.request("GET", "customers/"+id, "")
+	return stripe.GetCustomerCtx(context.Background(), id)
+}
+
+// GetCustomerCtx is like GetCustomer, but uses ctx for the request to Stripe.
+func (stripe *Stripe) GetCustomerCtx(ctx context.Context, id string) (resp *Customer, err error) {
+	r, err := stripe.request(ctx, "GET", "customers/"+id, "")
 	if err != nil {
 		return nil, err
 	}
@@ -153,30 +181,35 @@ func (stripe *Stripe) GetCustomer(id string) (resp *Customer, err error) {
 		return nil, err
 	}
 	if resp.Error != nil {
-		err = resp.Error
+		return nil, resp.Error.typed(200)
 	}
-	return resp, err
+	return resp, nil
 }
 
 // DeleteCustomer permanently deletes the Customer with ID of id from Stripe. It cannot be undone.
 func (stripe *Stripe) DeleteCustomer(id string) (success bool, err error) {
-	r, err := stripe.request("DELETE", "customers/"+id, "")
+	return stripe.DeleteCustomerCtx(context.Background(), id)
+}
+
+// DeleteCustomerCtx is like DeleteCustomer, but uses ctx for the request to Stripe.
+func (stripe *Stripe) DeleteCustomerCtx(ctx context.Context, id string) (success bool, err error) {
+	r, err := stripe.request(ctx, "DELETE", "customers/"+id, "")
 	if err != nil {
 		return false, err
 	}
 	var raw struct {
-		Success bool      "deleted"
-		ID      string    "id"
-		Error   *RawError "error"
+		Success bool      `json:"deleted"`
+		ID      string    `json:"id"`
+		Error   *RawError `json:"error"`
 	}
 	err = json.Unmarshal(r, &raw)
 	if err != nil {
 		return false, err
 	}
 	if raw.Error != nil {
-		// TODO: throw an error
+		return false, raw.Error.typed(200)
 	}
-	return raw.Success, err
+	return raw.Success, nil
 }
 
 // ListCustomers queries the server for information about all your Customers. Results are returned sorted by creation date, with the most recently created Customers appearing first.
@@ -184,9 +217,20 @@ func (stripe *Stripe) DeleteCustomer(id string) (success bool, err error) {
 // Both the arguments are optional.
 //
 // Pass -1 to count to use the Stripe default (10). Count determines the number of customers to return. The maximum is 100.
-// 
+//
 // Pass -1 to offset to use the Stripe default (0). Offset determines the number of recent customers to skip.
 func (stripe *Stripe) ListCustomers(count, offset int) (resp []*Customer, err error) {
+	return stripe.ListCustomersCtx(context.Background(), count, offset)
+}
+
+// ListCustomersCtx is like ListCustomers, but uses ctx for the request to Stripe.
+func (stripe *Stripe) ListCustomersCtx(ctx context.Context, count, offset int) (resp []*Customer, err error) {
+	resp, _, err = stripe.listCustomers(ctx, count, offset)
+	return
+}
+
+// listCustomers returns a page of Customers, along with the total number of Customers that match.
+func (stripe *Stripe) listCustomers(ctx context.Context, count, offset int) (resp []*Customer, total int, err error) {
 	values := make(url.Values)
 	if count >= 0 {
 		values.Set("count", strconv.Itoa(count))
@@ -198,22 +242,33 @@ func (stripe *Stripe) ListCustomers(count, offset int) (resp []*Customer, err er
 	if params != "" {
 		params = "?" + params
 	}
-	r, err := stripe.request("GET", "customers"+params, "")
+	r, err := stripe.request(ctx, "GET", "customers"+params, "")
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	var raw struct {
-		Count int         "count"
-		Data  []*Customer "data"
-		Error *RawError   "error"
+		Count int         `json:"count"`
+		Data  []*Customer `json:"data"`
+		Error *RawError   `json:"error"`
 	}
 	err = json.Unmarshal(r, &raw)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	if raw.Error != nil {
-		// TODO: throw an error
+		return nil, 0, raw.Error.typed(200)
 	}
-	resp = raw.Data
-	return
+	return raw.Data, raw.Count, nil
+}
+
+// IterCustomers returns an *Iter over all of your Customers.
+func (stripe *Stripe) IterCustomers() *Iter[*Customer] {
+	return stripe.IterCustomersCtx(context.Background())
+}
+
+// IterCustomersCtx is like IterCustomers, but uses ctx for every request to Stripe.
+func (stripe *Stripe) IterCustomersCtx(ctx context.Context) *Iter[*Customer] {
+	return newIter(ctx, func(ctx context.Context, count, offset int) ([]*Customer, int, error) {
+		return stripe.listCustomers(ctx, count, offset)
+	})
 }
@@ -0,0 +1,140 @@
+package stripe
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// EventHandlerFunc handles an Event received from Stripe.
+type EventHandlerFunc func(ctx context.Context, event *Event) error
+
+// Dispatcher routes Events to the handlers registered for their Type. It is
+// shared by WebhookHandler and anything else that receives Events, and is safe
+// for concurrent use.
+type Dispatcher struct {
+	// Store, if set, is consulted so that each Event is handled only once, no
+	// matter how many times it is dispatched.
+	Store ProcessedEventStore
+
+	mu       sync.RWMutex
+	handlers []eventHandler
+}
+
+type eventHandler struct {
+	pattern string
+	fn      EventHandlerFunc
+}
+
+// NewDispatcher returns a *Dispatcher with no handlers registered.
+func NewDispatcher() *Dispatcher {
+	return &Dispatcher{}
+}
+
+// Handle registers fn to be called for every Event whose Type matches
+// eventType, which may be a wildcard such as "charge.*"; see MatchEventType.
+// The handlers matching an Event are called in the order they were registered.
+func (d *Dispatcher) Handle(eventType string, fn EventHandlerFunc) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.handlers = append(d.handlers, eventHandler{eventType, fn})
+}
+
+// Dispatch calls the handlers matching event's Type, stopping at the first to
+// return an error. Events with no handlers are ignored.
+//
+// If d.Store is set, Events that have already been processed are skipped, and
+// ErrEventInProgress is returned for Events being handled by another call, so
+// that the caller can try again later.
+func (d *Dispatcher) Dispatch(ctx context.Context, event *Event) error {
+	if d.Store == nil {
+		return d.dispatch(ctx, event)
+	}
+	err := d.Store.Claim(ctx, event.ID)
+	if err == ErrEventProcessed {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	err = d.dispatch(ctx, event)
+	if err != nil {
+		if releaseErr := d.Store.Release(ctx, event.ID); releaseErr != nil {
+			return errors.Join(err, releaseErr)
+		}
+		return err
+	}
+	return d.Store.Complete(ctx, event.ID)
+}
+
+func (d *Dispatcher) dispatch(ctx context.Context, event *Event) error {
+	d.mu.RLock()
+	handlers := d.handlers
+	d.mu.RUnlock()
+	for _, handler := range handlers {
+		if !MatchEventType(handler.pattern, event.Type) {
+			continue
+		}
+		err := handler.fn(ctx, event)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// handleObject registers fn to be called with the object of every Event whose
+// Type matches eventType and whose object is a T. Events with other objects are
+// skipped rather than treated as failures, as a wildcard such as "customer.*"
+// matches Events about more than one type of object.
+func handleObject[T any](d *Dispatcher, eventType string, fn func(ctx context.Context, event *Event, object T) error) {
+	d.Handle(eventType, func(ctx context.Context, event *Event) error {
+		object, ok := event.Data.Object.(T)
+		if !ok {
+			return nil
+		}
+		return fn(ctx, event, object)
+	})
+}
+
+// HandleCharge registers fn to be called with the *Charge of every Event whose
+// Type matches eventType, e.g. EventChargeSucceeded.
+// Matching Events about other objects are skipped.
+func (d *Dispatcher) HandleCharge(eventType string, fn func(ctx context.Context, event *Event, charge *Charge) error) {
+	handleObject(d, eventType, fn)
+}
+
+// HandleCustomer registers fn to be called with the *Customer of every Event
+// whose Type matches eventType, e.g. EventCustomerCreated.
+// Matching Events about other objects are skipped.
+func (d *Dispatcher) HandleCustomer(eventType string, fn func(ctx context.Context, event *Event, customer *Customer) error) {
+	handleObject(d, eventType, fn)
+}
+
+// HandleInvoice registers fn to be called with the *Invoice of every Event
+// whose Type matches eventType, e.g. EventInvoicePaymentFailed.
+// Matching Events about other objects are skipped.
+func (d *Dispatcher) HandleInvoice(eventType string, fn func(ctx context.Context, event *Event, invoice *Invoice) error) {
+	handleObject(d, eventType, fn)
+}
+
+// HandleSubscription registers fn to be called with the *Subscription of every
+// Event whose Type matches eventType, e.g. EventCustomerSubscriptionDeleted.
+// Matching Events about other objects are skipped.
+func (d *Dispatcher) HandleSubscription(eventType string, fn func(ctx context.Context, event *Event, subscription *Subscription) error) {
+	handleObject(d, eventType, fn)
+}
+
+// HandlePlan registers fn to be called with the *Plan of every Event whose
+// Type matches eventType, e.g. EventPlanCreated.
+// Matching Events about other objects are skipped.
+func (d *Dispatcher) HandlePlan(eventType string, fn func(ctx context.Context, event *Event, plan *Plan) error) {
+	handleObject(d, eventType, fn)
+}
+
+// HandleCoupon registers fn to be called with the *Coupon of every Event whose
+// Type matches eventType, e.g. EventCouponDeleted.
+// Matching Events about other objects are skipped.
+func (d *Dispatcher) HandleCoupon(eventType string, fn func(ctx context.Context, event *Event, coupon *Coupon) error) {
+	handleObject(d, eventType, fn)
+}
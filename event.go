@@ -1,26 +1,133 @@
 package stripe
 
 import (
+	"context"
 	"encoding/json"
 	"net/url"
 	"strconv"
+	"strings"
 )
 
+// Event represents something that happened in your Stripe account, according to the Stripe API.
 type Event struct {
-        Type string `json:"type"`
-	Data struct {
-                Object interface{} `json:"object"`
+	Type            string    `json:"type"`
+	Data            EventData `json:"data"`
+	PendingWebhooks int       `json:"pending_webhooks"`
+	LiveMode        bool      `json:"livemode"`
+	Created         int64     `json:"created"`
+	ID              string    `json:"id"`
+	Object          string    `json:"object"`
+	Error           *RawError `json:"error"`
+	rawObject
+}
+
+// EventData holds the object an Event is about. Object is decoded into the
+// concrete type matching the Event's Type: a *Charge for "charge.*" events, a
+// *Customer for "customer.*" events, a *Subscription for
+// "customer.subscription.*" events, and so on. Objects of types this package
+// doesn't model are decoded as a map[string]interface{}.
+type EventData struct {
+	Object interface{} `json:"object"`
+	// Raw holds the undecoded JSON of Object.
+	Raw json.RawMessage `json:"-"`
+	rawObject
+}
+
+// MarshalJSON implements json.Marshaler.
+func (eventData EventData) MarshalJSON() ([]byte, error) {
+	type plainEventData EventData
+	return encodeRaw(plainEventData(eventData), eventData.rawObject)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (event *Event) UnmarshalJSON(data []byte) error {
+	type plainEvent Event
+	var raw struct {
+		plainEvent
+		Data json.RawMessage `json:"data"`
 	}
-        PendingWebhooks int       `json:"pending_webhooks"`
-        LiveMode        bool      `json:"livemode"`
-        Created         int64       `json:"created"`
-        ID              string    `json:"id"`
-        Object          string    `json:"object"`
-        Error           *RawError `json:"error"`
+	err := json.Unmarshal(data, &raw)
+	if err != nil {
+		return err
+	}
+	*event = Event(raw.plainEvent)
+	if len(raw.Data) > 0 {
+		var eventData struct {
+			Object json.RawMessage `json:"object"`
+		}
+		err = json.Unmarshal(raw.Data, &eventData)
+		if err != nil {
+			return err
+		}
+		event.Data.Raw = eventData.Object
+		event.Data.Object, err = decodeEventObject(event.Type, eventData.Object)
+		if err != nil {
+			return err
+		}
+		type plainEventData EventData
+		err = event.Data.rawObject.keep(raw.Data, plainEventData(event.Data))
+		if err != nil {
+			return err
+		}
+	}
+	// The Event is kept only once its data has been decoded, so that it's
+	// marshaled as it was decoded.
+	return event.rawObject.keep(data, plainEvent(*event))
+}
+
+// MarshalJSON implements json.Marshaler.
+func (event Event) MarshalJSON() ([]byte, error) {
+	type plainEvent Event
+	return encodeRaw(plainEvent(event), event.rawObject)
+}
+
+// decodeEventObject decodes data into the type of object events of eventType are about.
+func decodeEventObject(eventType string, data json.RawMessage) (interface{}, error) {
+	if len(data) == 0 || string(data) == "null" {
+		return nil, nil
+	}
+	var object interface{}
+	switch {
+	case strings.HasPrefix(eventType, "customer.subscription."):
+		object = &Subscription{}
+	case strings.HasPrefix(eventType, "customer.card."):
+		object = &Card{}
+	case strings.HasPrefix(eventType, "customer.discount."):
+		object = &Discount{}
+	case strings.HasPrefix(eventType, "customer."):
+		object = &Customer{}
+	case strings.HasPrefix(eventType, "charge.dispute."):
+		object = &map[string]interface{}{}
+	case strings.HasPrefix(eventType, "charge."):
+		object = &Charge{}
+	case strings.HasPrefix(eventType, "invoice."):
+		object = &Invoice{}
+	case strings.HasPrefix(eventType, "invoiceitem."):
+		object = &InvoiceItem{}
+	case strings.HasPrefix(eventType, "plan."):
+		object = &Plan{}
+	case strings.HasPrefix(eventType, "coupon."):
+		object = &Coupon{}
+	default:
+		object = &map[string]interface{}{}
+	}
+	err := json.Unmarshal(data, object)
+	if err != nil {
+		return nil, err
+	}
+	if m, ok := object.(*map[string]interface{}); ok {
+		return *m, nil
+	}
+	return object, nil
 }
 
 func (stripe *Stripe) GetEvent(id string) (resp *Event, err error) {
-	r, err := stripe.request("GET", "events/"+id, "")
+	return stripe.GetEventCtx(context.Background(), id)
+}
+
+// GetEventCtx is like GetEvent, but uses ctx for the request to Stripe.
+func (stripe *Stripe) GetEventCtx(ctx context.Context, id string) (resp *Event, err error) {
+	r, err := stripe.request(ctx, "GET", "events/"+id, "")
 	if err != nil {
 		return nil, err
 	}
@@ -29,12 +136,116 @@ func (stripe *Stripe) GetEvent(id string) (resp *Event, err error) {
 		return nil, err
 	}
 	if resp.Error != nil {
-		//TODO: Throw an error
+		return nil, resp.Error.typed(200)
+	}
+	return
+}
+
+// EventFilter narrows the Events returned by ListEventsFiltered and IterEvents.
+// Zero-valued fields are ignored.
+type EventFilter struct {
+	// Type limits the Events to those of a single type, e.g. EventChargeSucceeded,
+	// or of every type matching a wildcard, e.g. "charge.*".
+	Type string
+	// Created limits the Events to those created at exactly this UTC timestamp.
+	// It can't be combined with the range fields below.
+	Created int64
+	// CreatedGT, CreatedGTE, CreatedLT, and CreatedLTE limit the Events to those
+	// created after, at or after, before, or at or before these UTC timestamps.
+	CreatedGT  int64
+	CreatedGTE int64
+	CreatedLT  int64
+	CreatedLTE int64
+}
+
+// Values assigns the applicable properties of *filter to the appropriate keys
+// in *values.
+func (filter *EventFilter) Values(values *url.Values) error {
+	if filter == nil {
+		return nil
+	}
+	if filter.Type != "" {
+		values.Set("type", filter.Type)
 	}
+	ranged := false
+	for _, bound := range []struct {
+		key   string
+		value int64
+	}{
+		{"created[gt]", filter.CreatedGT},
+		{"created[gte]", filter.CreatedGTE},
+		{"created[lt]", filter.CreatedLT},
+		{"created[lte]", filter.CreatedLTE},
+	} {
+		if bound.value > 0 {
+			values.Set(bound.key, strconv.FormatInt(bound.value, 10))
+			ranged = true
+		}
+	}
+	if filter.Created > 0 {
+		if ranged {
+			return invalid("created", "an exact creation time can't be combined with a range")
+		}
+		values.Set("created", strconv.FormatInt(filter.Created, 10))
+	}
+	return nil
+}
+
+// ListEvents queries the server for Events, newest first.
+//
+// Deprecated: event and comparison ("gt", "gte", "lt", "lte", or "e" for an
+// exact match) are easily misused; use ListEventsFiltered instead.
+func (stripe *Stripe) ListEvents(event *Event, count, offset int, comparison string) (resp []*Event, err error) {
+	return stripe.ListEventsCtx(context.Background(), event, count, offset, comparison)
+}
+
+// ListEventsCtx is like ListEvents, but uses ctx for the request to Stripe.
+//
+// Deprecated: use ListEventsFilteredCtx instead.
+func (stripe *Stripe) ListEventsCtx(ctx context.Context, event *Event, count, offset int, comparison string) (resp []*Event, err error) {
+	var filter *EventFilter
+	if event != nil {
+		filter = &EventFilter{Type: event.Type}
+		if event.Created > 0 {
+			switch comparison {
+			case "":
+			case "e":
+				filter.Created = event.Created
+			case "gt":
+				filter.CreatedGT = event.Created
+			case "gte":
+				filter.CreatedGTE = event.Created
+			case "lt":
+				filter.CreatedLT = event.Created
+			case "lte":
+				filter.CreatedLTE = event.Created
+			default:
+				return nil, invalid("comparison", `must be "gt", "gte", "lt", "lte", or "e"`)
+			}
+		}
+	}
+	resp, _, err = stripe.listEvents(ctx, filter, count, offset)
 	return
 }
 
-func (stripe *Stripe) ListEvents(event *Event, count, offset int, comparison string ) (resp []*Event, err error) {
+// ListEventsFiltered queries the server for the Events matching filter, newest
+// first. Pass a nil filter to list every Event.
+//
+// Pass -1 to count to use the Stripe default (10). Count determines the number of Events to return. The maximum is 100.
+//
+// Pass -1 to offset to use the Stripe default (0). Offset determines the number of recent Events to skip.
+func (stripe *Stripe) ListEventsFiltered(filter *EventFilter, count, offset int) (resp []*Event, err error) {
+	return stripe.ListEventsFilteredCtx(context.Background(), filter, count, offset)
+}
+
+// ListEventsFilteredCtx is like ListEventsFiltered, but uses ctx for the request to Stripe.
+func (stripe *Stripe) ListEventsFilteredCtx(ctx context.Context, filter *EventFilter, count, offset int) (resp []*Event, err error) {
+	resp, _, err = stripe.listEvents(ctx, filter, count, offset)
+	return
+}
+
+// listEvents returns a page of Events, along with the total number of Events that match.
+func (stripe *Stripe) listEvents(ctx context.Context, filter *EventFilter, count, offset int) (resp []*Event, total int, err error) {
 	values := make(url.Values)
 	if count >= 0 {
 		values.Set("count", strconv.Itoa(count))
@@ -42,38 +253,42 @@ func (stripe *Stripe) ListEvents(event *Event, count, offset int, comparison str
 	if offset >= 0 {
 		values.Set("offset", strconv.Itoa(offset))
 	}
-        if event != nil {
-        	if event.Type != "" {
-	        	values.Set("type", event.Type)
-        	}
-	        if event.Created > 0 && comparison != "" {
-                        if comparison != "e" {
-        		        values.Set("created["+comparison+"]", strconv.FormatInt(event.Created, 10))
-                        } else {
-                                values.Set("created", strconv.FormatInt(event.Created, 10))
-                        }
-        	}
-        }
+	err = filter.Values(&values)
+	if err != nil {
+		return nil, 0, err
+	}
 	params := values.Encode()
 	if params != "" {
 		params = "?" + params
 	}
-	r, err := stripe.request("GET", "events"+params, "")
+	r, err := stripe.request(ctx, "GET", "events"+params, "")
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	var raw struct {
-		Count int       "count"
-		Data  []*Event  "data"
-		Error *RawError "error"
+		Count int       `json:"count"`
+		Data  []*Event  `json:"data"`
+		Error *RawError `json:"error"`
 	}
 	err = json.Unmarshal(r, &raw)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	if raw.Error != nil {
-		// TODO: throw an error
+		return nil, 0, raw.Error.typed(200)
 	}
-	resp = raw.Data
-	return
+	return raw.Data, raw.Count, nil
+}
+
+// IterEvents returns an *Iter over all of your Events that match filter, newest
+// first. Pass a nil filter to iterate over every Event.
+func (stripe *Stripe) IterEvents(filter *EventFilter) *Iter[*Event] {
+	return stripe.IterEventsCtx(context.Background(), filter)
+}
+
+// IterEventsCtx is like IterEvents, but uses ctx for every request to Stripe.
+func (stripe *Stripe) IterEventsCtx(ctx context.Context, filter *EventFilter) *Iter[*Event] {
+	return newIter(ctx, func(ctx context.Context, count, offset int) ([]*Event, int, error) {
+		return stripe.listEvents(ctx, filter, count, offset)
+	})
 }
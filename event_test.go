@@ -1,9 +1,55 @@
 package stripe
 
 import (
-	"io/ioutil"
 	"testing"
 )
 
-//TODO: TestGetEvent
-//TODO: TestListEvent
+func TestGetEvent(t *testing.T) {
+	API, _ := emulate(t)
+	customer, err := API.CreateCustomer(&Customer{Email: "oso@example.com"}, nil, "", "", -1)
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	events, err := API.ListEventsFiltered(&EventFilter{Type: EventCustomerCreated}, -1, -1)
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	if len(events) != 1 {
+		t.Fatalf("len(events) is %v, expected %v", len(events), 1)
+	}
+	event, err := API.GetEvent(events[0].ID)
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	got, ok := event.Data.Object.(*Customer)
+	if !ok {
+		t.Fatalf("Data.Object is %T, expected %T", event.Data.Object, got)
+	}
+	if got.ID != customer.ID || got.Email != customer.Email {
+		t.Errorf("Data.Object is %+v, expected %+v", got, customer)
+	}
+}
+
+func TestListEvents(t *testing.T) {
+	API, _ := emulate(t)
+	if _, err := API.CreatePlan(GOLD); err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	if _, err := API.CreateCharge(VALID, 1000, "usd", ""); err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	events, err := API.ListEventsFiltered(&EventFilter{Type: "charge.*"}, -1, -1)
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	if len(events) != 1 || events[0].Type != EventChargeSucceeded {
+		t.Errorf("events is %v, expected only a %v event", events, EventChargeSucceeded)
+	}
+	events, err = API.ListEventsFiltered(nil, -1, -1)
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	if len(events) != 2 {
+		t.Errorf("len(events) is %v, expected %v", len(events), 2)
+	}
+}
@@ -0,0 +1,178 @@
+package stripe
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+var (
+	// ErrEventProcessed is returned by ProcessedEventStore.Claim when the Event
+	// has already been processed.
+	ErrEventProcessed = errors.New("stripe: event has already been processed")
+	// ErrEventInProgress is returned by ProcessedEventStore.Claim when the Event
+	// is being processed by someone else.
+	ErrEventInProgress = errors.New("stripe: event is already being processed")
+)
+
+// ProcessedEventStore records which Events have been processed, so a Dispatcher
+// can handle each Event exactly once even though Stripe may deliver it more
+// than once.
+//
+// Processing an Event takes three steps: Claim it, handle it, then Complete it
+// or, if handling failed, Release it so a later delivery can try again. Claim
+// must be atomic, so that when two deliveries of an Event race, only one of
+// them handles it.
+type ProcessedEventStore interface {
+	// Claim marks the Event with ID id as in progress. It returns
+	// ErrEventProcessed if the Event has been completed, and ErrEventInProgress
+	// if it has been claimed but not yet completed or released.
+	Claim(ctx context.Context, id string) error
+	// Complete marks a claimed Event as processed.
+	Complete(ctx context.Context, id string) error
+	// Release gives up the claim on an Event, so it can be claimed again.
+	Release(ctx context.Context, id string) error
+}
+
+// MemoryEventStore is a ProcessedEventStore that keeps its records in memory.
+// They are lost when the process exits, and are never pruned.
+type MemoryEventStore struct {
+	mu     sync.Mutex
+	events map[string]bool // true once processed, false while in progress
+}
+
+// NewMemoryEventStore returns an empty *MemoryEventStore.
+func NewMemoryEventStore() *MemoryEventStore {
+	return &MemoryEventStore{events: make(map[string]bool)}
+}
+
+func (store *MemoryEventStore) Claim(ctx context.Context, id string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	processed, ok := store.events[id]
+	switch {
+	case processed:
+		return ErrEventProcessed
+	case ok:
+		return ErrEventInProgress
+	}
+	store.events[id] = false
+	return nil
+}
+
+func (store *MemoryEventStore) Complete(ctx context.Context, id string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	store.events[id] = true
+	return nil
+}
+
+func (store *MemoryEventStore) Release(ctx context.Context, id string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if !store.events[id] {
+		delete(store.events, id)
+	}
+	return nil
+}
+
+// FileEventStore is a ProcessedEventStore that keeps its records as files in
+// Dir, so they survive restarts and can be shared by every process with access
+// to Dir. Claims are made by exclusively creating a file, which is atomic on
+// local filesystems.
+//
+// A claim held by a process that exits before completing or releasing it stays
+// in place; remove its ".claim" file to let the Event be processed again.
+type FileEventStore struct {
+	Dir string
+}
+
+// NewFileEventStore returns a *FileEventStore keeping its records in dir,
+// creating dir if it doesn't exist.
+func NewFileEventStore(dir string) (*FileEventStore, error) {
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return nil, err
+	}
+	return &FileEventStore{Dir: dir}, nil
+}
+
+func (store *FileEventStore) path(id, suffix string) (string, error) {
+	if id == "" {
+		return "", invalid("id", "the event has no ID")
+	}
+	for _, r := range id {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && (r < '0' || r > '9') && r != '_' && r != '-' {
+			return "", invalid("id", "the event ID contains unexpected characters")
+		}
+	}
+	return filepath.Join(store.Dir, id+suffix), nil
+}
+
+func (store *FileEventStore) Claim(ctx context.Context, id string) error {
+	done, err := store.path(id, ".done")
+	if err != nil {
+		return err
+	}
+	claim, _ := store.path(id, ".claim")
+	f, err := os.OpenFile(claim, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if errors.Is(err, os.ErrExist) {
+		if _, err = os.Stat(done); err == nil {
+			return ErrEventProcessed
+		}
+		return ErrEventInProgress
+	}
+	if err != nil {
+		return err
+	}
+	err = f.Close()
+	if err != nil {
+		return err
+	}
+	// The Event is only checked for being processed once the claim is held, as
+	// another delivery may complete it, and remove its claim, at any point
+	// before then.
+	if _, err = os.Stat(done); err == nil {
+		err = os.Remove(claim)
+		if err != nil {
+			return err
+		}
+		return ErrEventProcessed
+	}
+	return nil
+}
+
+func (store *FileEventStore) Complete(ctx context.Context, id string) error {
+	done, err := store.path(id, ".done")
+	if err != nil {
+		return err
+	}
+	claim, _ := store.path(id, ".claim")
+	f, err := os.OpenFile(done, os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	err = f.Close()
+	if err != nil {
+		return err
+	}
+	err = os.Remove(claim)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (store *FileEventStore) Release(ctx context.Context, id string) error {
+	claim, err := store.path(id, ".claim")
+	if err != nil {
+		return err
+	}
+	err = os.Remove(claim)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
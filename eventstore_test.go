@@ -0,0 +1,121 @@
+package stripe
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func testEventStore(t *testing.T, store ProcessedEventStore) {
+	ctx := context.Background()
+	if err := store.Claim(ctx, "evt_1"); err != nil {
+		t.Fatalf("Claim: err = %v, want %v", err, nil)
+	}
+	if err := store.Claim(ctx, "evt_1"); err != ErrEventInProgress {
+		t.Errorf("second Claim: err = %v, want %v", err, ErrEventInProgress)
+	}
+	if err := store.Release(ctx, "evt_1"); err != nil {
+		t.Fatalf("Release: err = %v, want %v", err, nil)
+	}
+	if err := store.Claim(ctx, "evt_1"); err != nil {
+		t.Fatalf("Claim after Release: err = %v, want %v", err, nil)
+	}
+	if err := store.Complete(ctx, "evt_1"); err != nil {
+		t.Fatalf("Complete: err = %v, want %v", err, nil)
+	}
+	if err := store.Claim(ctx, "evt_1"); err != ErrEventProcessed {
+		t.Errorf("Claim after Complete: err = %v, want %v", err, ErrEventProcessed)
+	}
+	if err := store.Release(ctx, "evt_1"); err != nil {
+		t.Fatalf("Release after Complete: err = %v, want %v", err, nil)
+	}
+	if err := store.Claim(ctx, "evt_1"); err != ErrEventProcessed {
+		t.Errorf("Claim after Release of a completed event: err = %v, want %v", err, ErrEventProcessed)
+	}
+
+	// Only one of many racing deliveries of an event may handle it.
+	dispatcher := NewDispatcher()
+	dispatcher.Store = store
+	var handled int32
+	dispatcher.Handle("charge.succeeded", func(ctx context.Context, event *Event) error {
+		atomic.AddInt32(&handled, 1)
+		return nil
+	})
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := dispatcher.Dispatch(ctx, &Event{ID: "evt_2", Type: "charge.succeeded"})
+			if err != nil && err != ErrEventInProgress {
+				t.Errorf("Dispatch: err = %v, want %v or %v", err, nil, ErrEventInProgress)
+			}
+		}()
+	}
+	wg.Wait()
+	if err := dispatcher.Dispatch(ctx, &Event{ID: "evt_2", Type: "charge.succeeded"}); err != nil {
+		t.Errorf("Dispatch after handling: err = %v, want %v", err, nil)
+	}
+	if handled != 1 {
+		t.Errorf("handled is %v, expected %v", handled, 1)
+	}
+}
+
+func TestMemoryEventStore(t *testing.T) {
+	testEventStore(t, NewMemoryEventStore())
+}
+
+func TestFileEventStore(t *testing.T) {
+	store, err := NewFileEventStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	testEventStore(t, store)
+
+	// A delivery that completes the event just before another claims it
+	// leaves a record of it being processed, but no claim.
+	done, _ := store.path("evt_4", ".done")
+	if err := os.WriteFile(done, nil, 0600); err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	if err := store.Claim(context.Background(), "evt_4"); err != ErrEventProcessed {
+		t.Errorf("Claim of a just completed event: err = %v, want %v", err, ErrEventProcessed)
+	}
+	claim, _ := store.path("evt_4", ".claim")
+	if _, err := os.Stat(claim); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("claim left behind: err = %v, want %v", err, os.ErrNotExist)
+	}
+
+	var validationErr *ValidationError
+	if err := store.Claim(context.Background(), "../evt_3"); !errors.As(err, &validationErr) {
+		t.Errorf("err = %v, want a *ValidationError", err)
+	}
+}
+
+func TestDispatcherRetriesFailedEvents(t *testing.T) {
+	dispatcher := NewDispatcher()
+	dispatcher.Store = NewMemoryEventStore()
+	attempts := 0
+	dispatcher.Handle("invoice.payment_failed", func(ctx context.Context, event *Event) error {
+		attempts++
+		if attempts == 1 {
+			return errors.New("mail server unavailable")
+		}
+		return nil
+	})
+	event := &Event{ID: "evt_1", Type: "invoice.payment_failed"}
+	if err := dispatcher.Dispatch(context.Background(), event); err == nil {
+		t.Fatalf("err = %v, want an error", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := dispatcher.Dispatch(context.Background(), event); err != nil {
+			t.Fatalf("err = %v, want %v", err, nil)
+		}
+	}
+	if attempts != 2 {
+		t.Errorf("attempts is %v, expected %v", attempts, 2)
+	}
+}
@@ -0,0 +1,66 @@
+package stripe
+
+import "strings"
+
+// Event types, as found in Event.Type. See https://stripe.com/docs/api#event_types.
+const (
+	EventAccountUpdated                   = "account.updated"
+	EventAccountApplicationDeauthorized   = "account.application.deauthorized"
+	EventBalanceAvailable                 = "balance.available"
+	EventChargeSucceeded                  = "charge.succeeded"
+	EventChargeFailed                     = "charge.failed"
+	EventChargeRefunded                   = "charge.refunded"
+	EventChargeCaptured                   = "charge.captured"
+	EventChargeExpired                    = "charge.expired"
+	EventChargeUpdated                    = "charge.updated"
+	EventChargeDisputeCreated             = "charge.dispute.created"
+	EventChargeDisputeUpdated             = "charge.dispute.updated"
+	EventChargeDisputeClosed              = "charge.dispute.closed"
+	EventCustomerCreated                  = "customer.created"
+	EventCustomerUpdated                  = "customer.updated"
+	EventCustomerDeleted                  = "customer.deleted"
+	EventCustomerCardCreated              = "customer.card.created"
+	EventCustomerCardUpdated              = "customer.card.updated"
+	EventCustomerCardDeleted              = "customer.card.deleted"
+	EventCustomerSubscriptionCreated      = "customer.subscription.created"
+	EventCustomerSubscriptionUpdated      = "customer.subscription.updated"
+	EventCustomerSubscriptionDeleted      = "customer.subscription.deleted"
+	EventCustomerSubscriptionTrialWillEnd = "customer.subscription.trial_will_end"
+	EventCustomerDiscountCreated          = "customer.discount.created"
+	EventCustomerDiscountUpdated          = "customer.discount.updated"
+	EventCustomerDiscountDeleted          = "customer.discount.deleted"
+	EventInvoiceCreated                   = "invoice.created"
+	EventInvoiceUpdated                   = "invoice.updated"
+	EventInvoicePaymentSucceeded          = "invoice.payment_succeeded"
+	EventInvoicePaymentFailed             = "invoice.payment_failed"
+	EventInvoiceItemCreated               = "invoiceitem.created"
+	EventInvoiceItemUpdated               = "invoiceitem.updated"
+	EventInvoiceItemDeleted               = "invoiceitem.deleted"
+	EventPlanCreated                      = "plan.created"
+	EventPlanUpdated                      = "plan.updated"
+	EventPlanDeleted                      = "plan.deleted"
+	EventCouponCreated                    = "coupon.created"
+	EventCouponDeleted                    = "coupon.deleted"
+	EventTransferCreated                  = "transfer.created"
+	EventTransferUpdated                  = "transfer.updated"
+	EventTransferPaid                     = "transfer.paid"
+	EventTransferFailed                   = "transfer.failed"
+	EventPing                             = "ping"
+)
+
+// EventAll is a wildcard matching every event type.
+const EventAll = "*"
+
+// MatchEventType reports whether eventType matches pattern, which is either an
+// event type or a wildcard: "*" matches every type, and a pattern ending in
+// ".*", e.g. "charge.*", matches every type beginning with what precedes the
+// "*", e.g. "charge.succeeded" and "charge.dispute.created".
+func MatchEventType(pattern, eventType string) bool {
+	if pattern == EventAll {
+		return true
+	}
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok && strings.HasSuffix(prefix, ".") {
+		return strings.HasPrefix(eventType, prefix)
+	}
+	return pattern == eventType
+}
@@ -0,0 +1,103 @@
+package stripe
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestMatchEventType(t *testing.T) {
+	tests := []struct {
+		pattern, eventType string
+		want               bool
+	}{
+		{EventChargeSucceeded, "charge.succeeded", true},
+		{EventChargeSucceeded, "charge.failed", false},
+		{"charge.*", EventChargeSucceeded, true},
+		{"charge.*", EventChargeDisputeCreated, true},
+		{"charge.*", EventCustomerCreated, false},
+		{"customer.subscription.*", EventCustomerSubscriptionDeleted, true},
+		{"customer.subscription.*", EventCustomerCreated, false},
+		{EventAll, EventPing, true},
+		{"charge*", EventChargeSucceeded, false},
+	}
+	for _, test := range tests {
+		if got := MatchEventType(test.pattern, test.eventType); got != test.want {
+			t.Errorf("MatchEventType(%q, %q) is %v, expected %v", test.pattern, test.eventType, got, test.want)
+		}
+	}
+}
+
+func TestDispatcherWildcards(t *testing.T) {
+	dispatcher := NewDispatcher()
+	var calls []string
+	dispatcher.Handle("charge.*", func(ctx context.Context, event *Event) error {
+		calls = append(calls, "charge.*")
+		return nil
+	})
+	dispatcher.Handle(EventChargeFailed, func(ctx context.Context, event *Event) error {
+		calls = append(calls, EventChargeFailed)
+		return nil
+	})
+	dispatcher.Handle(EventAll, func(ctx context.Context, event *Event) error {
+		calls = append(calls, EventAll)
+		return nil
+	})
+	for _, eventType := range []string{EventChargeFailed, EventChargeSucceeded, EventPing} {
+		if err := dispatcher.Dispatch(context.Background(), &Event{ID: "evt_123", Type: eventType}); err != nil {
+			t.Fatalf("err = %v, want %v", err, nil)
+		}
+	}
+	want := []string{"charge.*", EventChargeFailed, EventAll, "charge.*", EventAll, EventAll}
+	if len(calls) != len(want) {
+		t.Fatalf("calls are %q, expected %q", calls, want)
+	}
+	for i := range want {
+		if calls[i] != want[i] {
+			t.Errorf("calls are %q, expected %q", calls, want)
+			break
+		}
+	}
+}
+
+func TestEventFilterValues(t *testing.T) {
+	values := make(url.Values)
+	filter := &EventFilter{Type: "invoice.*", CreatedGTE: 1360000000, CreatedLT: 1370000000}
+	if err := filter.Values(&values); err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	want := "created%5Bgte%5D=1360000000&created%5Blt%5D=1370000000&type=invoice.%2A"
+	if got := values.Encode(); got != want {
+		t.Errorf("values are %v, expected %v", got, want)
+	}
+
+	filter = &EventFilter{Created: 1360000000, CreatedGT: 1350000000}
+	var validationErr *ValidationError
+	if err := filter.Values(&values); !errors.As(err, &validationErr) {
+		t.Errorf("err = %v, want a *ValidationError", err)
+	}
+}
+
+func TestListEventsComparison(t *testing.T) {
+	var query url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		w.Write([]byte(`{"object": "list", "count": 0, "data": []}`))
+	}))
+	defer server.Close()
+
+	API := New("sk_test_key", WithBaseURL(server.URL+"/v1/"))
+	if _, err := API.ListEvents(&Event{Type: EventPing, Created: 1360000000}, -1, -1, "lte"); err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	if query.Get("created[lte]") != "1360000000" || query.Get("type") != EventPing {
+		t.Errorf("query is %v, expected created[lte] and type to be set", query)
+	}
+	var validationErr *ValidationError
+	if _, err := API.ListEvents(&Event{Created: 1360000000}, -1, -1, "after"); !errors.As(err, &validationErr) {
+		t.Errorf("err = %v, want a *ValidationError", err)
+	}
+}
@@ -0,0 +1,240 @@
+package stripe
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// checkDecoded reports every difference between fixture, the JSON of a recorded
+// Stripe object, and decoded, the JSON the model re-encodes it as. A key or a
+// value the model drops or changes is a difference, as is a key the model adds.
+func checkDecoded(path string, fixture, decoded interface{}) []string {
+	switch f := fixture.(type) {
+	case map[string]interface{}:
+		d, ok := decoded.(map[string]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s is %v, expected an object", path, decoded)}
+		}
+		var diffs []string
+		for _, key := range sortedKeys(f) {
+			if _, ok := d[key]; !ok {
+				diffs = append(diffs, fmt.Sprintf("%s.%s is missing from the model", path, key))
+				continue
+			}
+			diffs = append(diffs, checkDecoded(path+"."+key, f[key], d[key])...)
+		}
+		for _, key := range sortedKeys(d) {
+			if _, ok := f[key]; !ok {
+				diffs = append(diffs, fmt.Sprintf("%s.%s is %v, expected it to be left out", path, key, d[key]))
+			}
+		}
+		return diffs
+	case []interface{}:
+		d, ok := decoded.([]interface{})
+		if !ok || len(d) != len(f) {
+			return []string{fmt.Sprintf("%s is %v, expected %v", path, decoded, fixture)}
+		}
+		var diffs []string
+		for i := range f {
+			diffs = append(diffs, checkDecoded(fmt.Sprintf("%s[%d]", path, i), f[i], d[i])...)
+		}
+		return diffs
+	case nil:
+		if decoded != nil {
+			return []string{fmt.Sprintf("%s is %v, expected null", path, decoded)}
+		}
+		return nil
+	}
+	if !reflect.DeepEqual(fixture, decoded) {
+		return []string{fmt.Sprintf("%s is %v, expected %v", path, decoded, fixture)}
+	}
+	return nil
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	return data
+}
+
+func TestFixturesDecodeCompletely(t *testing.T) {
+	tests := []struct {
+		fixture string
+		model   interface{}
+	}{
+		{"card.json", &Card{}},
+		{"token.json", &Token{}},
+		{"charge.json", &Charge{}},
+		{"customer.json", &Customer{}},
+		{"plan.json", &Plan{}},
+		{"coupon.json", &Coupon{}},
+		{"discount.json", &Discount{}},
+		{"subscription.json", &Subscription{}},
+		{"invoice.json", &Invoice{}},
+		{"invoiceitem.json", &InvoiceItem{}},
+		{"event.json", &Event{}},
+		{"refund.json", &Refund{}},
+	}
+	for _, test := range tests {
+		data := readFixture(t, test.fixture)
+		if err := json.Unmarshal(data, test.model); err != nil {
+			t.Errorf("%s: err = %v, want %v", test.fixture, err, nil)
+			continue
+		}
+		if raw := test.model.(interface{ RawJSON() json.RawMessage }).RawJSON(); !bytes.Equal(raw, bytes.TrimSpace(data)) {
+			t.Errorf("%s: RawJSON is %s, expected the fixture", test.fixture, raw)
+		}
+		encoded, err := json.Marshal(test.model)
+		if err != nil {
+			t.Errorf("%s: err = %v, want %v", test.fixture, err, nil)
+			continue
+		}
+		var fixture, decoded interface{}
+		json.Unmarshal(data, &fixture)
+		json.Unmarshal(encoded, &decoded)
+		for _, diff := range checkDecoded(test.fixture, fixture, decoded) {
+			t.Error(diff)
+		}
+	}
+}
+
+func TestCouponListDecodes(t *testing.T) {
+	data := readFixture(t, "coupon_list.json")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(data)
+	}))
+	defer server.Close()
+	API := New("sk_test_key", WithBaseURL(server.URL+"/v1/"))
+	coupons, total, err := API.listCoupons(context.Background(), -1, -1)
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	if total != 2 {
+		t.Errorf("total is %v, expected %v", total, 2)
+	}
+	if len(coupons) != 2 {
+		t.Fatalf("len(coupons) is %v, expected %v", len(coupons), 2)
+	}
+	want := Coupon{ID: "SPRING25", Object: "coupon", PercentOff: 25, Duration: "repeating", DurationInMonths: 3, MaxRedemptions: 100, RedeemBy: 1893456000, TimesRedeemed: 7}
+	want.rawObject = coupons[0].rawObject
+	if !reflect.DeepEqual(*coupons[0], want) {
+		t.Errorf("coupons[0] is %+v, expected %+v", *coupons[0], want)
+	}
+}
+
+func TestUnknownFields(t *testing.T) {
+	var charge Charge
+	if err := json.Unmarshal(readFixture(t, "charge.json"), &charge); err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	charge.Description = "Platinum plan"
+	encoded, err := json.Marshal(charge)
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	var decoded struct {
+		Description        string `json:"description"`
+		BalanceTransaction string `json:"balance_transaction"`
+		Card               struct {
+			AddressCity string `json:"address_city"`
+		} `json:"card"`
+	}
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	if decoded.Description != "Platinum plan" {
+		t.Errorf("description is %v, expected %v", decoded.Description, "Platinum plan")
+	}
+	if decoded.BalanceTransaction != "txn_2LmkvFkTi6C6hK" {
+		t.Errorf("balance_transaction is %v, expected %v", decoded.BalanceTransaction, "txn_2LmkvFkTi6C6hK")
+	}
+	if decoded.Card.AddressCity != "San Francisco" {
+		t.Errorf("card.address_city is %v, expected %v", decoded.Card.AddressCity, "San Francisco")
+	}
+
+	// An object that wasn't decoded has no raw JSON, and nothing to add to its own.
+	plan := Plan{ID: "gold"}
+	if raw := plan.RawJSON(); raw != nil {
+		t.Errorf("RawJSON is %s, expected %v", raw, nil)
+	}
+	encoded, err = json.Marshal(&plan)
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	type plainPlan Plan
+	want, err := json.Marshal(plainPlan(plan))
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	if !bytes.Equal(encoded, want) {
+		t.Errorf("plan is encoded as %s, expected %s", encoded, want)
+	}
+}
+
+func TestRemarshalKeepsFields(t *testing.T) {
+	data := []byte(`{"id":"SPRING25","object":"coupon","percent_off":25,"max_redemptions":null,"redeem_by":null}`)
+	var coupon Coupon
+	if err := json.Unmarshal(data, &coupon); err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	encoded, err := json.Marshal(coupon)
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	if string(encoded) != string(data) {
+		t.Errorf("coupon is encoded as %s, expected %s", encoded, data)
+	}
+
+	coupon.PercentOff = 50
+	coupon.RedeemBy = 1893456000
+	encoded, err = json.Marshal(coupon)
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	want := `{"id":"SPRING25","max_redemptions":null,"object":"coupon","percent_off":50,"redeem_by":1893456000}`
+	if string(encoded) != want {
+		t.Errorf("changed coupon is encoded as %s, expected %s", encoded, want)
+	}
+}
+
+func TestDecodedObjectsAreComparable(t *testing.T) {
+	data := readFixture(t, "card.json")
+	var a, b Card
+	if err := json.Unmarshal(data, &a); err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	if err := json.Unmarshal(data, &b); err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	if a != b {
+		t.Errorf("cards decoded from the same JSON are %+v and %+v, expected them to be ==", a, b)
+	}
+	b.Name = "Someone Else"
+	if a == b {
+		t.Errorf("cards with different names are ==, expected them not to be")
+	}
+	if (Card{ID: "card_123"}) != (Card{ID: "card_123"}) {
+		t.Errorf("identical cards aren't ==")
+	}
+}
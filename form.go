@@ -0,0 +1,143 @@
+package stripe
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// encodeForm sets the fields of v, a struct or pointer to a struct, to their
+// keys in *values, using Stripe's bracketed form encoding. Only fields with a
+// form tag are encoded; the tag gives the field's key, and may be followed by
+// ",omitempty" to leave the field out when it has its zero value:
+//
+//	Number string            `form:"number"`
+//	CVC    string            `form:"cvc,omitempty"`
+//	Card   *Card             `form:"card,omitempty"` // card[number], card[cvc]
+//	Items  []Item            `form:"items"`          // items[0][plan], items[1][plan]
+//	Meta   map[string]string `form:"metadata"`       // metadata[key]
+//
+// If prefix is non-empty, the keys are nested under it, as prefix[key].
+//
+// Pointers are followed, and nil pointers left out, so a *string can tell
+// leaving a parameter alone (nil) apart from unsetting it (a pointer to "").
+// Maps are encoded with their keys in order, and with empty values included,
+// since Stripe takes those as unsetting the key. A time.Time is encoded as a
+// Unix timestamp, and a Chargeable as whatever its ChargeValues method sets.
+func encodeForm(prefix string, v interface{}, values *url.Values) error {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("stripe: can't form-encode a %v", rv.Type())
+	}
+	return encodeStruct(prefix, rv, values)
+}
+
+func encodeStruct(prefix string, rv reflect.Value, values *url.Values) error {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		tag, ok := field.Tag.Lookup("form")
+		if !ok || tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		fv := rv.Field(i)
+		if opts == "omitempty" && isEmpty(fv) {
+			continue
+		}
+		if err := encodeValue(formKey(prefix, name), fv, values); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func formKey(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "[" + name + "]"
+}
+
+var (
+	chargeableType = reflect.TypeOf((*Chargeable)(nil)).Elem()
+	timeType       = reflect.TypeOf(time.Time{})
+)
+
+func encodeValue(key string, fv reflect.Value, values *url.Values) error {
+	if fv.Type().Implements(chargeableType) && (fv.Kind() != reflect.Pointer && fv.Kind() != reflect.Interface || !fv.IsNil()) {
+		return fv.Interface().(Chargeable).ChargeValues(values)
+	}
+	switch fv.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if fv.IsNil() {
+			return nil
+		}
+		return encodeValue(key, fv.Elem(), values)
+	case reflect.Struct:
+		if fv.Type() == timeType {
+			if t := fv.Interface().(time.Time); !t.IsZero() {
+				values.Set(key, strconv.FormatInt(t.Unix(), 10))
+			}
+			return nil
+		}
+		return encodeStruct(key, fv, values)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < fv.Len(); i++ {
+			if err := encodeValue(key+"["+strconv.Itoa(i)+"]", fv.Index(i), values); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Map:
+		if fv.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("stripe: can't form-encode %v, a map with non-string keys", key)
+		}
+		keys := make([]string, 0, fv.Len())
+		for _, k := range fv.MapKeys() {
+			keys = append(keys, k.String())
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if err := encodeValue(key+"["+k+"]", fv.MapIndex(reflect.ValueOf(k).Convert(fv.Type().Key())), values); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.String:
+		values.Set(key, fv.String())
+	case reflect.Bool:
+		values.Set(key, strconv.FormatBool(fv.Bool()))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		values.Set(key, strconv.FormatInt(fv.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		values.Set(key, strconv.FormatUint(fv.Uint(), 10))
+	case reflect.Float32, reflect.Float64:
+		values.Set(key, strconv.FormatFloat(fv.Float(), 'f', -1, 64))
+	default:
+		return fmt.Errorf("stripe: can't form-encode %v, a %v", key, fv.Type())
+	}
+	return nil
+}
+
+// isEmpty reports whether fv is empty, for omitempty.
+func isEmpty(fv reflect.Value) bool {
+	switch fv.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return fv.Len() == 0
+	}
+	return fv.IsZero()
+}
@@ -0,0 +1,102 @@
+package stripe
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestEncodeForm(t *testing.T) {
+	type item struct {
+		Plan     string `form:"plan"`
+		Quantity int    `form:"quantity,omitempty"`
+	}
+	type shipping struct {
+		Name    string `form:"name"`
+		Carrier string `form:"carrier,omitempty"`
+	}
+	empty := ""
+	note := "gift"
+	params := struct {
+		Amount      int               `form:"amount"`
+		Capture     bool              `form:"capture"`
+		Rate        float64           `form:"rate,omitempty"`
+		Description *string           `form:"description,omitempty"`
+		Note        *string           `form:"note,omitempty"`
+		Unset       *string           `form:"unset,omitempty"`
+		Shipping    *shipping         `form:"shipping,omitempty"`
+		Items       []item            `form:"items"`
+		Metadata    map[string]string `form:"metadata,omitempty"`
+		TrialEnd    time.Time         `form:"trial_end,omitempty"`
+		Source      Chargeable        `form:"card,omitempty"`
+		Ignored     string
+		Skipped     string `form:"-"`
+	}{
+		Amount:   2000,
+		Note:     &note,
+		Unset:    &empty,
+		Shipping: &shipping{Name: "Oso"},
+		Items:    []item{{Plan: "gold", Quantity: 2}, {Plan: "silver"}},
+		Metadata: map[string]string{"b": "", "a": "1"},
+		TrialEnd: time.Unix(1700000000, 0),
+		Source:   &Token{ID: "tok_123"},
+		Ignored:  "ignored",
+		Skipped:  "skipped",
+	}
+	values := make(url.Values)
+	if err := encodeForm("", &params, &values); err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	want := url.Values{
+		"amount":             {"2000"},
+		"capture":            {"false"},
+		"note":               {"gift"},
+		"unset":              {""},
+		"shipping[name]":     {"Oso"},
+		"items[0][plan]":     {"gold"},
+		"items[0][quantity]": {"2"},
+		"items[1][plan]":     {"silver"},
+		"metadata[a]":        {"1"},
+		"metadata[b]":        {""},
+		"trial_end":          {"1700000000"},
+		"card":               {"tok_123"},
+	}
+	if values.Encode() != want.Encode() {
+		t.Errorf("values are %v, expected %v", values.Encode(), want.Encode())
+	}
+}
+
+func TestEncodeFormPrefix(t *testing.T) {
+	values := make(url.Values)
+	err := encodeForm("card", &Card{Number: "4242424242424242", ExpMonth: 3, ExpYear: 2030, LastFour: "4242"}, &values)
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	if got, want := values.Encode(), "card%5Bexp_month%5D=3&card%5Bexp_year%5D=2030&card%5Bnumber%5D=4242424242424242"; got != want {
+		t.Errorf("values are %v, expected %v", got, want)
+	}
+}
+
+func TestEncodeFormUnsupported(t *testing.T) {
+	values := make(url.Values)
+	params := struct {
+		Callback func() `form:"callback"`
+	}{func() {}}
+	if err := encodeForm("", params, &values); err == nil {
+		t.Errorf("err = %v, want an error for a func field", err)
+	}
+	if err := encodeForm("", "not a struct", &values); err == nil {
+		t.Errorf("err = %v, want an error for a string", err)
+	}
+}
+
+func TestCouponValues(t *testing.T) {
+	values := make(url.Values)
+	coupon := &Coupon{ID: "SPRING", PercentOff: 25, Duration: "repeating", DurationInMonths: 3, MaxRedemptions: 10}
+	if err := coupon.Values(&values); err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	if got, want := values.Encode(), "duration=repeating&duration_in_months=3&id=SPRING&max_redemptions=10&percent_off=25"; got != want {
+		t.Errorf("values are %v, expected %v", got, want)
+	}
+}
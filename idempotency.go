@@ -0,0 +1,47 @@
+package stripe
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+type idempotencyKeyContextKey struct{}
+
+// ContextWithIdempotencyKey returns a copy of ctx that makes any POST request
+// sent with it carry key as its Idempotency-Key header. Stripe returns the
+// original response for every repeat of a request with the same key, so a
+// request that may or may not have reached Stripe can be safely sent again:
+//
+//	ctx = stripe.ContextWithIdempotencyKey(ctx, "order-1234")
+//	charge, err := API.CreateChargeCtx(ctx, token, 500, "usd", "")
+func ContextWithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKeyContextKey{}, key)
+}
+
+func idempotencyKey(ctx context.Context) string {
+	key, _ := ctx.Value(idempotencyKeyContextKey{}).(string)
+	return key
+}
+
+// WithAutoIdempotencyKeys makes the *Stripe generate an Idempotency-Key for
+// every POST request that wasn't given one with ContextWithIdempotencyKey. The
+// key is reused when the request is retried, which also makes POST requests
+// eligible for retries under the *Stripe's RetryPolicy.
+func WithAutoIdempotencyKeys() Option {
+	return func(stripe *Stripe) {
+		stripe.AutoIdempotencyKeys = true
+	}
+}
+
+// newIdempotencyKey returns a random version 4 UUID.
+func newIdempotencyKey() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	h := hex.EncodeToString(b[:])
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:], nil
+}
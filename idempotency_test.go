@@ -0,0 +1,60 @@
+package stripe
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIdempotencyKey(t *testing.T) {
+	var keys []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+		w.Write([]byte(`{"id": "ch_123", "object": "charge"}`))
+	}))
+	defer server.Close()
+
+	API := New("sk_test_key", WithBaseURL(server.URL+"/v1/"))
+	ctx := ContextWithIdempotencyKey(context.Background(), "order-1234")
+	_, err := API.CreateChargeCtx(ctx, &Token{ID: "tok_123"}, 500, "usd", "")
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	_, err = API.CreateCharge(&Token{ID: "tok_123"}, 500, "usd", "")
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	_, err = API.CreateChargeWithParams(&ChargeParams{Amount: 500, Currency: "usd", Source: &Token{ID: "tok_123"}, IdempotencyKey: "order-5678"})
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	if len(keys) != 3 || keys[0] != "order-1234" || keys[1] != "" || keys[2] != "order-5678" {
+		t.Errorf("keys are %q, expected %q", keys, []string{"order-1234", "", "order-5678"})
+	}
+}
+
+func TestAutoIdempotencyKeyReusedOnRetry(t *testing.T) {
+	var keys []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+		if len(keys) == 1 {
+			w.WriteHeader(502)
+			return
+		}
+		w.Write([]byte(`{"id": "cus_123", "object": "customer"}`))
+	}))
+	defer server.Close()
+
+	API := New("sk_test_key", WithBaseURL(server.URL+"/v1/"), WithRetryPolicy(testRetryPolicy), WithAutoIdempotencyKeys())
+	_, err := API.CreateCustomer(&Customer{Email: "oso@example.com"}, nil, "", "", -1)
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	if len(keys) != 2 {
+		t.Fatalf("requests is %v, expected %v", len(keys), 2)
+	}
+	if keys[0] == "" || keys[0] != keys[1] {
+		t.Errorf("keys are %q, expected one generated key sent twice", keys)
+	}
+}
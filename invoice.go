@@ -1,50 +1,82 @@
 package stripe
 
 import (
+	"context"
 	"encoding/json"
 	"net/url"
 	"strconv"
 )
 
 type Invoice struct {
-        ID                 string  `json:"id"`
-        LiveMode           bool    `json:"livemode"`
-        AmountDue          int     `json:"amount_due"`
-        AttemptCount       int     `json:"attempt_count"`
-        Attempted          bool    `json:"attempted"`
-        Closed             bool    `json:"closed"`
-        CustomerID         string  `json:"customer"`
-        Date               int64   `json:"date"`
-        Paid               bool    `json:"paid"`
-        PeriodEnd          int64   `json:"period_end"`
-        PeriodStart        int64   `json:"period_start"`
-        StartingBalance    int     `json:"starting_balance"`
-        Subtotal           int     `json:"subtotal"`
-        Total              int     `json:"total"`
-        ChargeID           *string `json:"charge"`
-        Discount           *Discount `json:"discount"`
-        EndingBalance      *int    `json:"ending_balance"`
-        NextPaymentAttempt *int    `json:"next_payment_attempt"`
+	ID                 string    `json:"id"`
+	LiveMode           bool      `json:"livemode"`
+	AmountDue          int       `json:"amount_due"`
+	AttemptCount       int       `json:"attempt_count"`
+	Attempted          bool      `json:"attempted"`
+	Closed             bool      `json:"closed"`
+	CustomerID         string    `json:"customer"`
+	Date               int64     `json:"date"`
+	Paid               bool      `json:"paid"`
+	PeriodEnd          int64     `json:"period_end"`
+	PeriodStart        int64     `json:"period_start"`
+	StartingBalance    int       `json:"starting_balance"`
+	Subtotal           int       `json:"subtotal"`
+	Total              int       `json:"total"`
+	ChargeID           *string   `json:"charge"`
+	Discount           *Discount `json:"discount"`
+	EndingBalance      *int      `json:"ending_balance"`
+	NextPaymentAttempt *int      `json:"next_payment_attempt"`
 	Lines              struct {
-                InvoiceItems  []*InvoiceItem `json:"invoiceitems"`
-                Subscriptions []*SubscriptionItem `json:"subscriptions"`
-                Prorated      []*InvoiceItem `json:"prorations"`
-	}
-        Object string    `json:"object"`
-        Error  *RawError `json:"error"`
+		InvoiceItems  []*InvoiceItem      `json:"invoiceitems"`
+		Subscriptions []*SubscriptionItem `json:"subscriptions"`
+		Prorated      []*InvoiceItem      `json:"prorations"`
+	} `json:"lines"`
+	Object string    `json:"object"`
+	Error  *RawError `json:"error"`
+	rawObject
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (invoice *Invoice) UnmarshalJSON(data []byte) error {
+	type plainInvoice Invoice
+	return decodeRaw(data, (*plainInvoice)(invoice), &invoice.rawObject)
+}
+
+// MarshalJSON implements json.Marshaler.
+func (invoice Invoice) MarshalJSON() ([]byte, error) {
+	type plainInvoice Invoice
+	return encodeRaw(plainInvoice(invoice), invoice.rawObject)
 }
 
 type SubscriptionItem struct {
-        Amount             int     `json:"amount"`
-        Period             struct {
-                Start      int64   `json:"start"`
-                End        int64   `json:"end"`
-        }                          `json:"period"`
-        Plan               *Plan   `json:"plan"`
+	Amount int `json:"amount"`
+	Period struct {
+		Start int64 `json:"start"`
+		End   int64 `json:"end"`
+	} `json:"period"`
+	Plan *Plan `json:"plan"`
+	rawObject
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (item *SubscriptionItem) UnmarshalJSON(data []byte) error {
+	type plainSubscriptionItem SubscriptionItem
+	return decodeRaw(data, (*plainSubscriptionItem)(item), &item.rawObject)
+}
+
+// MarshalJSON implements json.Marshaler.
+func (item SubscriptionItem) MarshalJSON() ([]byte, error) {
+	type plainSubscriptionItem SubscriptionItem
+	return encodeRaw(plainSubscriptionItem(item), item.rawObject)
 }
 
 func (stripe *Stripe) GetInvoice(id string) (resp *Invoice, err error) {
-	r, err := stripe.request("GET", "invoices/"+id, "")
+	return stripe.GetInvoiceCtx(context.Background(), id)
+}
+
+// GetInvoiceCtx is like GetInvoice, but uses ctx for the request to Stripe.
+func (stripe *Stripe) GetInvoiceCtx(ctx context.Context, id string) (resp *Invoice, err error) {
+	r, err := stripe.request(ctx, "GET", "invoices/"+id, "")
 	if err != nil {
 		return nil, err
 	}
@@ -53,16 +85,21 @@ func (stripe *Stripe) GetInvoice(id string) (resp *Invoice, err error) {
 		return nil, err
 	}
 	if resp.Error != nil {
-		// TODO: Throw an error
+		return nil, resp.Error.typed(200)
 	}
 	return
 }
 
 func (stripe *Stripe) GetNextInvoice(customer string) (resp *Invoice, err error) {
+	return stripe.GetNextInvoiceCtx(context.Background(), customer)
+}
+
+// GetNextInvoiceCtx is like GetNextInvoice, but uses ctx for the request to Stripe.
+func (stripe *Stripe) GetNextInvoiceCtx(ctx context.Context, customer string) (resp *Invoice, err error) {
 	values := make(url.Values)
 	values.Set("customer", customer)
 	params := values.Encode()
-	r, err := stripe.request("GET", "invoices/upcoming"+params, "")
+	r, err := stripe.request(ctx, "GET", "invoices/upcoming?"+params, "")
 	if err != nil {
 		return nil, err
 	}
@@ -71,12 +108,23 @@ func (stripe *Stripe) GetNextInvoice(customer string) (resp *Invoice, err error)
 		return nil, err
 	}
 	if resp.Error != nil {
-		// TODO: Throw an error
+		return nil, resp.Error.typed(200)
 	}
 	return
 }
 
 func (stripe *Stripe) ListInvoices(count, offset int, customer string) (resp []*Invoice, err error) {
+	return stripe.ListInvoicesCtx(context.Background(), count, offset, customer)
+}
+
+// ListInvoicesCtx is like ListInvoices, but uses ctx for the request to Stripe.
+func (stripe *Stripe) ListInvoicesCtx(ctx context.Context, count, offset int, customer string) (resp []*Invoice, err error) {
+	resp, _, err = stripe.listInvoices(ctx, count, offset, customer)
+	return
+}
+
+// listInvoices returns a page of Invoices, along with the total number of Invoices that match.
+func (stripe *Stripe) listInvoices(ctx context.Context, count, offset int, customer string) (resp []*Invoice, total int, err error) {
 	values := make(url.Values)
 	if count >= 0 {
 		values.Set("count", strconv.Itoa(count))
@@ -91,72 +139,85 @@ func (stripe *Stripe) ListInvoices(count, offset int, customer string) (resp []*
 	if params != "" {
 		params = "?" + params
 	}
-	r, err := stripe.request("GET", "invoices"+params, "")
+	r, err := stripe.request(ctx, "GET", "invoices"+params, "")
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	var raw struct {
-		Count int        "count"
-		Data  []*Invoice "data"
-		Error *RawError  "error"
+		Count int        `json:"count"`
+		Data  []*Invoice `json:"data"`
+		Error *RawError  `json:"error"`
 	}
 	err = json.Unmarshal(r, &raw)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	if raw.Error != nil {
-		// TODO: throw an error
+		return nil, 0, raw.Error.typed(200)
 	}
-	resp = raw.Data
-	return
+	return raw.Data, raw.Count, nil
 }
 
 type InvoiceItem struct {
-        ID          string    `json:"id"`
-        LiveMode    bool      `json:"livemode"`
-        Date        int64     `json:"date"`
-        Description *string   `json:"description"`
-        Currency    string    `json:"currency"`
-        Amount      int       `json:"amount"`
-        CustomerID  string    `json:"customer"`
-        InvoiceID   *string   `json:"invoice"`
-        Object      int       `json:"object"`
-        Error       *RawError `json:"error"`
+	ID          string            `json:"id"`
+	LiveMode    bool              `json:"livemode"`
+	Date        int64             `json:"date"`
+	Description *string           `json:"description" form:"description,omitempty"`
+	Currency    string            `json:"currency" form:"currency"`
+	Amount      int               `json:"amount" form:"amount"`
+	CustomerID  string            `json:"customer" form:"customer"`
+	InvoiceID   *string           `json:"invoice" form:"invoice,omitempty"`
+	Object      string            `json:"object"` // Should always be "invoiceitem"
+	Metadata    map[string]string `json:"metadata" form:"metadata,omitempty"`
+	Error       *RawError         `json:"error"`
+	rawObject
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (item *InvoiceItem) UnmarshalJSON(data []byte) error {
+	type plainInvoiceItem InvoiceItem
+	return decodeRaw(data, (*plainInvoiceItem)(item), &item.rawObject)
+}
+
+// MarshalJSON implements json.Marshaler.
+func (item InvoiceItem) MarshalJSON() ([]byte, error) {
+	type plainInvoiceItem InvoiceItem
+	return encodeRaw(plainInvoiceItem(item), item.rawObject)
 }
 
 func (item *InvoiceItem) Values(values *url.Values) error {
-        if item == nil {
-                //TODO: Throw error
-        }
-        if item.CustomerID == "" {
-                // TODO: Throw error
-        }
-        if item.Amount == 0 {
-                // TODO: Throw error
-        }
-        if item.Currency != "USD" {
-                // TODO: Throw error
-        }
-        if item.InvoiceID != nil {
-                values.Set("invoice", *item.InvoiceID)
-        }
-        if item.Description != nil {
-                values.Set("desription", *item.Description)
-        }
-        values.Set("customer", item.CustomerID)
-        values.Set("amount", strconv.Itoa(item.Amount))
-        values.Set("currency", item.Currency)
-        return nil
+	if item == nil {
+		return invalid("invoiceitem", "no invoice item was provided")
+	}
+	if item.CustomerID == "" {
+		return invalid("customer", "the invoice item has no customer ID")
+	}
+	if item.Amount == 0 {
+		return invalid("amount", "must not be zero")
+	}
+	if !validCurrency(item.Currency) {
+		return invalid("currency", "must be a three-letter ISO currency code")
+	}
+	err := validMetadata(item.Metadata)
+	if err != nil {
+		return err
+	}
+	return encodeForm("", item, values)
 }
 
 func (stripe *Stripe) CreateInvoiceItem(item *InvoiceItem) (resp *InvoiceItem, err error) {
-        values := make(url.Values)
-        err = item.Values(&values)
-        if err != nil {
-                return nil, err
-        }
+	return stripe.CreateInvoiceItemCtx(context.Background(), item)
+}
+
+// CreateInvoiceItemCtx is like CreateInvoiceItem, but uses ctx for the request to Stripe.
+func (stripe *Stripe) CreateInvoiceItemCtx(ctx context.Context, item *InvoiceItem) (resp *InvoiceItem, err error) {
+	values := make(url.Values)
+	err = item.Values(&values)
+	if err != nil {
+		return nil, err
+	}
 	data := values.Encode()
-	r, err := stripe.request("POST", "invoiceitems", data)
+	r, err := stripe.request(ctx, "POST", "invoiceitems", data)
 	if err != nil {
 		return nil, err
 	}
@@ -165,13 +226,18 @@ func (stripe *Stripe) CreateInvoiceItem(item *InvoiceItem) (resp *InvoiceItem, e
 		return nil, err
 	}
 	if resp.Error != nil {
-		// TODO: Throw an error
+		return nil, resp.Error.typed(200)
 	}
 	return
 }
 
 func (stripe *Stripe) GetInvoiceItem(id string) (resp *InvoiceItem, err error) {
-	r, err := stripe.request("GET", "invoiceitems/"+id, "")
+	return stripe.GetInvoiceItemCtx(context.Background(), id)
+}
+
+// GetInvoiceItemCtx is like GetInvoiceItem, but uses ctx for the request to Stripe.
+func (stripe *Stripe) GetInvoiceItemCtx(ctx context.Context, id string) (resp *InvoiceItem, err error) {
+	r, err := stripe.request(ctx, "GET", "invoiceitems/"+id, "")
 	if err != nil {
 		return nil, err
 	}
@@ -180,12 +246,17 @@ func (stripe *Stripe) GetInvoiceItem(id string) (resp *InvoiceItem, err error) {
 		return nil, err
 	}
 	if resp.Error != nil {
-		// TODO: Throw an error
+		return nil, resp.Error.typed(200)
 	}
 	return
 }
 
 func (stripe *Stripe) UpdateInvoiceItem(id string, amount int, description string) (resp *InvoiceItem, err error) {
+	return stripe.UpdateInvoiceItemCtx(context.Background(), id, amount, description)
+}
+
+// UpdateInvoiceItemCtx is like UpdateInvoiceItem, but uses ctx for the request to Stripe.
+func (stripe *Stripe) UpdateInvoiceItemCtx(ctx context.Context, id string, amount int, description string) (resp *InvoiceItem, err error) {
 	values := make(url.Values)
 	if amount >= 0 {
 		values.Set("amount", strconv.Itoa(amount))
@@ -194,7 +265,7 @@ func (stripe *Stripe) UpdateInvoiceItem(id string, amount int, description strin
 		values.Set("description", description)
 	}
 	data := values.Encode()
-	r, err := stripe.request("POST", "invoiceitems/"+id, data)
+	r, err := stripe.request(ctx, "POST", "invoiceitems/"+id, data)
 	if err != nil {
 		return nil, err
 	}
@@ -203,32 +274,48 @@ func (stripe *Stripe) UpdateInvoiceItem(id string, amount int, description strin
 		return nil, err
 	}
 	if resp.Error != nil {
-		// TODO: Throw an error
+		return nil, resp.Error.typed(200)
 	}
 	return
 }
 
 func (stripe *Stripe) DeleteInvoiceItem(id string) (success bool, err error) {
-	r, err := stripe.request("DELETE", "invoiceitems/"+id, "")
+	return stripe.DeleteInvoiceItemCtx(context.Background(), id)
+}
+
+// DeleteInvoiceItemCtx is like DeleteInvoiceItem, but uses ctx for the request to Stripe.
+func (stripe *Stripe) DeleteInvoiceItemCtx(ctx context.Context, id string) (success bool, err error) {
+	r, err := stripe.request(ctx, "DELETE", "invoiceitems/"+id, "")
 	if err != nil {
 		return false, err
 	}
 	var raw struct {
-		Success bool      "deleted"
-		ID      string    "id"
-		Error   *RawError "error"
+		Success bool      `json:"deleted"`
+		ID      string    `json:"id"`
+		Error   *RawError `json:"error"`
 	}
 	err = json.Unmarshal(r, &raw)
 	if err != nil {
 		return false, err
 	}
 	if raw.Error != nil {
-		// TODO: throw an error
+		return false, raw.Error.typed(200)
 	}
-	return raw.Success, err
+	return raw.Success, nil
 }
 
 func (stripe *Stripe) ListInvoiceItems(count, offset int, customer string) (resp []*InvoiceItem, err error) {
+	return stripe.ListInvoiceItemsCtx(context.Background(), count, offset, customer)
+}
+
+// ListInvoiceItemsCtx is like ListInvoiceItems, but uses ctx for the request to Stripe.
+func (stripe *Stripe) ListInvoiceItemsCtx(ctx context.Context, count, offset int, customer string) (resp []*InvoiceItem, err error) {
+	resp, _, err = stripe.listInvoiceItems(ctx, count, offset, customer)
+	return
+}
+
+// listInvoiceItems returns a page of InvoiceItems, along with the total number of InvoiceItems that match.
+func (stripe *Stripe) listInvoiceItems(ctx context.Context, count, offset int, customer string) (resp []*InvoiceItem, total int, err error) {
 	values := make(url.Values)
 	if count >= 0 {
 		values.Set("count", strconv.Itoa(count))
@@ -243,22 +330,47 @@ func (stripe *Stripe) ListInvoiceItems(count, offset int, customer string) (resp
 	if params != "" {
 		params = "?" + params
 	}
-	r, err := stripe.request("GET", "invoiceitems"+params, "")
+	r, err := stripe.request(ctx, "GET", "invoiceitems"+params, "")
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	var raw struct {
-                Count int            `json:"count"`
-                Data  []*InvoiceItem `json:"data"`
-                Error *RawError      `json:"error"`
+		Count int            `json:"count"`
+		Data  []*InvoiceItem `json:"data"`
+		Error *RawError      `json:"error"`
 	}
 	err = json.Unmarshal(r, &raw)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	if raw.Error != nil {
-		// TODO: throw an error
+		return nil, 0, raw.Error.typed(200)
 	}
-	resp = raw.Data
-	return
+	return raw.Data, raw.Count, nil
+}
+
+// IterInvoices returns an *Iter over all of your Invoices.
+// If customer is non-empty, only that customer's Invoices are returned.
+func (stripe *Stripe) IterInvoices(customer string) *Iter[*Invoice] {
+	return stripe.IterInvoicesCtx(context.Background(), customer)
+}
+
+// IterInvoicesCtx is like IterInvoices, but uses ctx for every request to Stripe.
+func (stripe *Stripe) IterInvoicesCtx(ctx context.Context, customer string) *Iter[*Invoice] {
+	return newIter(ctx, func(ctx context.Context, count, offset int) ([]*Invoice, int, error) {
+		return stripe.listInvoices(ctx, count, offset, customer)
+	})
+}
+
+// IterInvoiceItems returns an *Iter over all of your InvoiceItems.
+// If customer is non-empty, only that customer's InvoiceItems are returned.
+func (stripe *Stripe) IterInvoiceItems(customer string) *Iter[*InvoiceItem] {
+	return stripe.IterInvoiceItemsCtx(context.Background(), customer)
+}
+
+// IterInvoiceItemsCtx is like IterInvoiceItems, but uses ctx for every request to Stripe.
+func (stripe *Stripe) IterInvoiceItemsCtx(ctx context.Context, customer string) *Iter[*InvoiceItem] {
+	return newIter(ctx, func(ctx context.Context, count, offset int) ([]*InvoiceItem, int, error) {
+		return stripe.listInvoiceItems(ctx, count, offset, customer)
+	})
 }
@@ -1,25 +1,112 @@
 package stripe
 
 import (
-	"io/ioutil"
+	"errors"
 	"testing"
 )
 
-// TODO: TestGetInvoice
-// TODO: TestGetNextInvoice
-// TODO: TestCreateInvoiceItem
-// TODO: TestGetInvoiceItem
-// TODO: TestUpdateInvoiceItem
-// TODO: TestDeleteInvoiceItem
-// TODO: TestListInvoiceItem
+func TestGetInvoice(t *testing.T) {
+	API, _ := emulate(t)
+	plan := &Plan{ID: "basic", Name: "Basic", Amount: 1000, Currency: "usd", Interval: "month"}
+	if _, err := API.CreatePlan(plan); err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	customer, err := API.CreateCustomer(&Customer{}, VALID, plan.ID, "", -1)
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	invoices, err := API.ListInvoices(-1, -1, customer.ID)
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	if len(invoices) != 1 {
+		t.Fatalf("len(invoices) is %v, expected %v", len(invoices), 1)
+	}
+	invoice, err := API.GetInvoice(invoices[0].ID)
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	if !invoice.Paid || invoice.Total != plan.Amount || invoice.ChargeID == nil {
+		t.Errorf("invoice is %+v, expected a paid, charged invoice for %v", invoice, plan.Amount)
+	}
+	if len(invoice.Lines.Subscriptions) != 1 || invoice.Lines.Subscriptions[0].Plan.ID != plan.ID {
+		t.Errorf("Lines.Subscriptions is %v, expected one for %v", invoice.Lines.Subscriptions, plan.ID)
+	}
+}
 
-func TestListInvoices(t *testing.T) {
-	key, err := ioutil.ReadFile("key")
+func TestGetNextInvoice(t *testing.T) {
+	API, _ := emulate(t)
+	customer, err := API.CreateCustomer(&Customer{}, VALID, "", "", -1)
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	_, err = API.GetNextInvoice(customer.ID)
+	var notFound *NotFoundError
+	if !errors.As(err, &notFound) {
+		t.Errorf("err = %v, want a *NotFoundError", err)
+	}
+	if _, err = API.CreateInvoiceItem(&InvoiceItem{CustomerID: customer.ID, Amount: 300, Currency: "usd"}); err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	invoice, err := API.GetNextInvoice(customer.ID)
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	if invoice.AmountDue != 300 {
+		t.Errorf("AmountDue is %v, expected %v", invoice.AmountDue, 300)
+	}
+}
+
+func TestInvoiceItems(t *testing.T) {
+	API, _ := emulate(t)
+	customer, err := API.CreateCustomer(&Customer{}, nil, "", "", -1)
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	description := "setup fee"
+	item, err := API.CreateInvoiceItem(&InvoiceItem{CustomerID: customer.ID, Amount: 1500, Currency: "usd", Description: &description})
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	if item.Description == nil || *item.Description != description {
+		t.Errorf("Description is %v, expected %v", item.Description, description)
+	}
+	item, err = API.UpdateInvoiceItem(item.ID, 1200, "")
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	if item.Amount != 1200 {
+		t.Errorf("Amount is %v, expected %v", item.Amount, 1200)
+	}
+	got, err := API.GetInvoiceItem(item.ID)
 	if err != nil {
 		t.Fatalf("err = %v, want %v", err, nil)
 	}
-	API := New(string(key))
-	_, err = API.ListInvoices()
+	if got.Amount != 1200 {
+		t.Errorf("Amount is %v, expected %v", got.Amount, 1200)
+	}
+	items, err := API.ListInvoiceItems(-1, -1, customer.ID)
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	if len(items) != 1 {
+		t.Errorf("len(items) is %v, expected %v", len(items), 1)
+	}
+	success, err := API.DeleteInvoiceItem(item.ID)
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	if !success {
+		t.Errorf("success is %v, expected %v", success, true)
+	}
+	if _, err = API.GetInvoiceItem(item.ID); err == nil {
+		t.Errorf("err = %v, want an error for a deleted invoice item", err)
+	}
+}
+
+func TestListInvoices(t *testing.T) {
+	API, _ := emulate(t)
+	_, err := API.ListInvoices(-1, -1, "")
 	if err != nil {
 		t.Fatalf("err = %v, want %v", err, nil)
 	}
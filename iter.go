@@ -0,0 +1,110 @@
+package stripe
+
+import (
+	"context"
+	"iter"
+)
+
+// iterPageSize is the number of objects an Iter requests per page; the most
+// Stripe allows.
+const iterPageSize = 100
+
+// Iter pages through every object in a list from the Stripe API, fetching a
+// new page only when the previous one has been consumed:
+//
+//	it := API.IterCharges("")
+//	for it.Next() {
+//		charge := it.Current()
+//		...
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+//
+// Stripe lists are paged by offset, newest first, so objects created while an
+// Iter is in use may cause others to be returned twice.
+type Iter[T any] struct {
+	ctx   context.Context
+	fetch func(ctx context.Context, count, offset int) ([]T, int, error)
+
+	page    []T
+	offset  int
+	total   int
+	fetched bool
+	done    bool
+	current T
+	err     error
+}
+
+func newIter[T any](ctx context.Context, fetch func(ctx context.Context, count, offset int) ([]T, int, error)) *Iter[T] {
+	return &Iter[T]{ctx: ctx, fetch: fetch}
+}
+
+// Next advances the Iter to the next object, which is then available through
+// Current. It returns false when there are no more objects or a request fails;
+// Err distinguishes between the two.
+func (it *Iter[T]) Next() bool {
+	for len(it.page) == 0 {
+		if it.done || it.err != nil {
+			return false
+		}
+		it.nextPage()
+	}
+	it.current = it.page[0]
+	it.page = it.page[1:]
+	return true
+}
+
+func (it *Iter[T]) nextPage() {
+	page, total, err := it.fetch(it.ctx, iterPageSize, it.offset)
+	if err != nil {
+		it.err = err
+		return
+	}
+	it.fetched = true
+	it.total = total
+	it.page = page
+	it.offset += len(page)
+	// Stripe leaves the count out of some lists, so it only ends the Iter when
+	// it was reported.
+	if len(page) < iterPageSize || (total > 0 && it.offset >= total) {
+		it.done = true
+	}
+}
+
+// Current returns the object Next advanced to.
+func (it *Iter[T]) Current() T {
+	return it.current
+}
+
+// Err returns the error that stopped the Iter, if any.
+func (it *Iter[T]) Err() error {
+	return it.err
+}
+
+// Count returns the total number of objects in the list, as reported by Stripe,
+// or 0 if Stripe didn't report it. It fetches the first page if Next hasn't been called yet, and returns 0 if
+// that request fails.
+func (it *Iter[T]) Count() int {
+	if !it.fetched && it.err == nil {
+		it.nextPage()
+	}
+	return it.total
+}
+
+// All returns an iterator over the remaining objects, for use with range. A
+// failed request is yielded as the final pair, with a zero object. Breaking out
+// of the loop stops the Iter from fetching any further pages.
+func (it *Iter[T]) All() iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for it.Next() {
+			if !yield(it.Current(), nil) {
+				return
+			}
+		}
+		if err := it.Err(); err != nil {
+			var zero T
+			yield(zero, err)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package stripe
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// listServer serves a list of total customers, paged by count and offset. The
+// total is reported as the list's count if withCount is true.
+func listServer(t *testing.T, total int, withCount bool, requests *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests++
+		count, _ := strconv.Atoi(r.URL.Query().Get("count"))
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		var data []string
+		for i := offset; i < offset+count && i < total; i++ {
+			data = append(data, fmt.Sprintf(`{"id": "cus_%d", "object": "customer"}`, i))
+		}
+		if withCount {
+			fmt.Fprintf(w, `{"object": "list", "count": %d, "data": [%s]}`, total, strings.Join(data, ","))
+			return
+		}
+		fmt.Fprintf(w, `{"object": "list", "data": [%s]}`, strings.Join(data, ","))
+	}))
+}
+
+func TestIterPagesThroughAll(t *testing.T) {
+	requests := 0
+	server := listServer(t, 250, true, &requests)
+	defer server.Close()
+
+	API := New("sk_test_key", WithBaseURL(server.URL+"/v1/"))
+	it := API.IterCustomers()
+	seen := 0
+	for it.Next() {
+		if want := fmt.Sprintf("cus_%d", seen); it.Current().ID != want {
+			t.Errorf("Current().ID is %v, expected %v", it.Current().ID, want)
+		}
+		seen++
+	}
+	if err := it.Err(); err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	if seen != 250 || it.Count() != 250 {
+		t.Errorf("seen, Count() are %v, %v, expected %v", seen, it.Count(), 250)
+	}
+	if requests != 3 {
+		t.Errorf("requests is %v, expected %v", requests, 3)
+	}
+}
+
+func TestIterWithoutCount(t *testing.T) {
+	requests := 0
+	server := listServer(t, 250, false, &requests)
+	defer server.Close()
+
+	API := New("sk_test_key", WithBaseURL(server.URL+"/v1/"))
+	it := API.IterCustomers()
+	seen := 0
+	for it.Next() {
+		seen++
+	}
+	if err := it.Err(); err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	if seen != 250 || it.Count() != 0 {
+		t.Errorf("seen, Count() are %v, %v, expected %v, %v", seen, it.Count(), 250, 0)
+	}
+	if requests != 3 {
+		t.Errorf("requests is %v, expected %v", requests, 3)
+	}
+}
+
+func TestIterAllStopsEarly(t *testing.T) {
+	requests := 0
+	server := listServer(t, 250, true, &requests)
+	defer server.Close()
+
+	API := New("sk_test_key", WithBaseURL(server.URL+"/v1/"))
+	seen := 0
+	for customer, err := range API.IterCustomers().All() {
+		if err != nil {
+			t.Fatalf("err = %v, want %v", err, nil)
+		}
+		if customer.ID == "cus_5" {
+			break
+		}
+		seen++
+	}
+	if seen != 5 || requests != 1 {
+		t.Errorf("seen, requests are %v, %v, expected %v, %v", seen, requests, 5, 1)
+	}
+}
+
+func TestIterError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(400)
+		w.Write([]byte(`{"error": {"type": "invalid_request_error", "message": "Invalid count."}}`))
+	}))
+	defer server.Close()
+
+	API := New("sk_test_key", WithBaseURL(server.URL+"/v1/"))
+	var last error
+	for _, err := range API.IterCharges("").All() {
+		last = err
+	}
+	var reqErr *InvalidRequestError
+	if !errors.As(last, &reqErr) {
+		t.Errorf("err = %#v, want an *InvalidRequestError", last)
+	}
+}
@@ -0,0 +1,131 @@
+package stripe
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"unicode/utf8"
+)
+
+// Stripe's limits on the metadata that can be attached to an object.
+const (
+	MaxMetadataKeys        = 50
+	MaxMetadataKeyLength   = 40
+	MaxMetadataValueLength = 500
+)
+
+// validMetadata checks metadata against Stripe's limits.
+func validMetadata(metadata map[string]string) error {
+	if len(metadata) > MaxMetadataKeys {
+		return invalid("metadata", fmt.Sprintf("must have at most %d keys", MaxMetadataKeys))
+	}
+	for key, value := range metadata {
+		if key == "" {
+			return invalid("metadata", "keys must not be empty")
+		}
+		if utf8.RuneCountInString(key) > MaxMetadataKeyLength {
+			return invalid("metadata["+key+"]", fmt.Sprintf("keys must be at most %d characters", MaxMetadataKeyLength))
+		}
+		if utf8.RuneCountInString(value) > MaxMetadataValueLength {
+			return invalid("metadata["+key+"]", fmt.Sprintf("values must be at most %d characters", MaxMetadataValueLength))
+		}
+	}
+	return nil
+}
+
+// metadataValues validates metadata, and sets each of its keys to the
+// appropriate metadata[key] key in *values. A key with an empty value is sent
+// empty, which unsets it on an existing object.
+func metadataValues(metadata map[string]string, values *url.Values) error {
+	err := validMetadata(metadata)
+	if err != nil {
+		return err
+	}
+	params := struct {
+		Metadata map[string]string `form:"metadata"`
+	}{metadata}
+	return encodeForm("", params, values)
+}
+
+// UpdateChargeMetadata sets the metadata of the Charge with an ID of id. Keys not
+// in metadata are left as they are; to unset a key, set its value to "".
+func (stripe *Stripe) UpdateChargeMetadata(id string, metadata map[string]string) (resp *Charge, err error) {
+	return stripe.UpdateChargeMetadataCtx(context.Background(), id, metadata)
+}
+
+// UpdateChargeMetadataCtx is like UpdateChargeMetadata, but uses ctx for the request to Stripe.
+func (stripe *Stripe) UpdateChargeMetadataCtx(ctx context.Context, id string, metadata map[string]string) (resp *Charge, err error) {
+	r, err := stripe.updateMetadata(ctx, "charges", id, metadata)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(r, &resp)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != nil {
+		return nil, resp.Error.typed(200)
+	}
+	return
+}
+
+// UpdatePlanMetadata sets the metadata of the Plan with an ID of id. Keys not
+// in metadata are left as they are; to unset a key, set its value to "".
+func (stripe *Stripe) UpdatePlanMetadata(id string, metadata map[string]string) (resp *Plan, err error) {
+	return stripe.UpdatePlanMetadataCtx(context.Background(), id, metadata)
+}
+
+// UpdatePlanMetadataCtx is like UpdatePlanMetadata, but uses ctx for the request to Stripe.
+func (stripe *Stripe) UpdatePlanMetadataCtx(ctx context.Context, id string, metadata map[string]string) (resp *Plan, err error) {
+	r, err := stripe.updateMetadata(ctx, "plans", id, metadata)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(r, &resp)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != nil {
+		return nil, resp.Error.typed(200)
+	}
+	return
+}
+
+// UpdateInvoiceItemMetadata sets the metadata of the InvoiceItem with an ID of id.
+// Keys not in metadata are left as they are; to unset a key, set its value to "".
+func (stripe *Stripe) UpdateInvoiceItemMetadata(id string, metadata map[string]string) (resp *InvoiceItem, err error) {
+	return stripe.UpdateInvoiceItemMetadataCtx(context.Background(), id, metadata)
+}
+
+// UpdateInvoiceItemMetadataCtx is like UpdateInvoiceItemMetadata, but uses ctx for the request to Stripe.
+func (stripe *Stripe) UpdateInvoiceItemMetadataCtx(ctx context.Context, id string, metadata map[string]string) (resp *InvoiceItem, err error) {
+	r, err := stripe.updateMetadata(ctx, "invoiceitems", id, metadata)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(r, &resp)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != nil {
+		return nil, resp.Error.typed(200)
+	}
+	return
+}
+
+// updateMetadata posts metadata to the object with an ID of id in collection.
+func (stripe *Stripe) updateMetadata(ctx context.Context, collection, id string, metadata map[string]string) ([]byte, error) {
+	if id == "" {
+		return nil, invalid("id", "no ID was provided")
+	}
+	if len(metadata) == 0 {
+		return nil, invalid("metadata", "no metadata was provided")
+	}
+	values := make(url.Values)
+	err := metadataValues(metadata, &values)
+	if err != nil {
+		return nil, err
+	}
+	return stripe.request(ctx, "POST", collection+"/"+id, values.Encode())
+}
@@ -0,0 +1,119 @@
+package stripe
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestMetadataValidation(t *testing.T) {
+	tooMany := make(map[string]string)
+	for i := 0; i <= MaxMetadataKeys; i++ {
+		tooMany["key"+strconv.Itoa(i)] = "value"
+	}
+	tests := []struct {
+		metadata map[string]string
+		field    string
+	}{
+		{tooMany, "metadata"},
+		{map[string]string{"": "value"}, "metadata"},
+		{map[string]string{strings.Repeat("k", MaxMetadataKeyLength+1): "value"}, "metadata[" + strings.Repeat("k", MaxMetadataKeyLength+1) + "]"},
+		{map[string]string{"key": strings.Repeat("v", MaxMetadataValueLength+1)}, "metadata[key]"},
+	}
+	for _, test := range tests {
+		values := make(url.Values)
+		err := metadataValues(test.metadata, &values)
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) {
+			t.Errorf("err = %v, want a *ValidationError", err)
+			continue
+		}
+		if validationErr.Field != test.field {
+			t.Errorf("Field is %v, expected %v", validationErr.Field, test.field)
+		}
+	}
+	values := make(url.Values)
+	err := metadataValues(map[string]string{"account": "acct_1", "gone": ""}, &values)
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	if got := values.Encode(); got != "metadata%5Baccount%5D=acct_1&metadata%5Bgone%5D=" {
+		t.Errorf("values are %v, expected metadata[account] and an empty metadata[gone]", got)
+	}
+}
+
+func TestCustomerMetadata(t *testing.T) {
+	API, _ := emulate(t)
+	customer, err := API.CreateCustomer(&Customer{Metadata: map[string]string{"account": "acct_1", "tier": "gold"}}, nil, "", "", -1)
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	if want := map[string]string{"account": "acct_1", "tier": "gold"}; !reflect.DeepEqual(customer.Metadata, want) {
+		t.Errorf("Metadata is %v, expected %v", customer.Metadata, want)
+	}
+	customer, err = API.UpdateCustomer(&Customer{ID: customer.ID, Metadata: map[string]string{"tier": "", "region": "eu"}}, nil, "")
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	if want := map[string]string{"account": "acct_1", "region": "eu"}; !reflect.DeepEqual(customer.Metadata, want) {
+		t.Errorf("Metadata is %v, expected %v", customer.Metadata, want)
+	}
+}
+
+func TestUpdateMetadata(t *testing.T) {
+	API, _ := emulate(t)
+	charge, err := API.CreateCharge(VALID, 2000, "usd", "")
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	charge, err = API.UpdateChargeMetadata(charge.ID, map[string]string{"order": "1234"})
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	if charge.Metadata["order"] != "1234" {
+		t.Errorf("Metadata is %v, expected order 1234", charge.Metadata)
+	}
+
+	plan := &Plan{ID: "basic", Name: "Basic", Amount: 500, Currency: "usd", Interval: "month", Metadata: map[string]string{"legacy": "true"}}
+	if _, err := API.CreatePlan(plan); err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	plan, err = API.UpdatePlanMetadata(plan.ID, map[string]string{"legacy": ""})
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	if len(plan.Metadata) != 0 {
+		t.Errorf("Metadata is %v, expected it to be empty", plan.Metadata)
+	}
+
+	customer, err := API.CreateCustomer(&Customer{}, VALID, "", "", -1)
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	item, err := API.CreateInvoiceItem(&InvoiceItem{CustomerID: customer.ID, Amount: 100, Currency: "usd", Metadata: map[string]string{"sku": "A1"}})
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	item, err = API.UpdateInvoiceItemMetadata(item.ID, map[string]string{"sku": "B2"})
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	if item.Metadata["sku"] != "B2" {
+		t.Errorf("Metadata is %v, expected sku B2", item.Metadata)
+	}
+
+	sub, err := API.Subscribe(&Subscription{CustomerID: customer.ID, Plan: plan, Metadata: map[string]string{"seats": "5"}}, "", true, nil)
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	if sub.Metadata["seats"] != "5" {
+		t.Errorf("Metadata is %v, expected seats 5", sub.Metadata)
+	}
+
+	if _, err := API.UpdateChargeMetadata(charge.ID, nil); err == nil {
+		t.Errorf("err = %v, want an error for empty metadata", err)
+	}
+}
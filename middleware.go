@@ -0,0 +1,192 @@
+package stripe
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Call describes a single round trip to the Stripe API. Method, Path, Body,
+// Header, and Attempt describe the request; StatusCode, RequestID, and Latency
+// are filled in once the round trip completes.
+type Call struct {
+	Method string
+	// Path is relative to the API's base URL, e.g. "charges/ch_123".
+	Path string
+	// Body is the URL-encoded request body. It may contain card details; pass it
+	// through RedactForm before logging it.
+	Body string
+	// Header holds the extra headers sent with the request. Middleware may add
+	// to it, e.g. to propagate a trace.
+	Header http.Header
+	// Attempt is 1 for the first attempt at a request, and increases with every retry.
+	Attempt int
+
+	// StatusCode is the HTTP status of the response, or 0 if none was received.
+	StatusCode int
+	// RequestID is the ID Stripe assigned the request, as sent in the Request-Id header.
+	RequestID string
+	// Latency is how long the round trip took.
+	Latency time.Duration
+
+	retryAfter time.Duration
+}
+
+// RoundTrip performs the round trip described by *call and returns the response body.
+type RoundTrip func(ctx context.Context, call *Call) ([]byte, error)
+
+// Middleware wraps the round trips a *Stripe makes to the Stripe API, to observe
+// or alter them. Wrap returns a RoundTrip that is expected to call next.
+type Middleware interface {
+	Wrap(next RoundTrip) RoundTrip
+}
+
+// MiddlewareFunc adapts a function to the Middleware interface.
+type MiddlewareFunc func(next RoundTrip) RoundTrip
+
+func (f MiddlewareFunc) Wrap(next RoundTrip) RoundTrip {
+	return f(next)
+}
+
+// roundTrip returns the *Stripe's Middleware chain wrapped around attempt.
+func (stripe *Stripe) roundTrip() RoundTrip {
+	roundTrip := RoundTrip(stripe.attempt)
+	for i := len(stripe.Middleware) - 1; i >= 0; i-- {
+		roundTrip = stripe.Middleware[i].Wrap(roundTrip)
+	}
+	return roundTrip
+}
+
+// endpoint names the API resource *call is for, without IDs or query
+// parameters, e.g. "GET charges", so it can be used as a metric label.
+func (call *Call) endpoint() string {
+	resource := call.Path
+	if i := strings.IndexAny(resource, "/?"); i >= 0 {
+		resource = resource[:i]
+	}
+	return call.Method + " " + resource
+}
+
+// LoggingMiddleware returns a Middleware that logs every round trip to logger.
+// Successful round trips are logged at slog.LevelInfo, failed ones at
+// slog.LevelError. Request bodies are logged at slog.LevelDebug, with card
+// details redacted.
+func LoggingMiddleware(logger *slog.Logger) Middleware {
+	return MiddlewareFunc(func(next RoundTrip) RoundTrip {
+		return func(ctx context.Context, call *Call) ([]byte, error) {
+			resp, err := next(ctx, call)
+			attrs := []slog.Attr{
+				slog.String("method", call.Method),
+				slog.String("path", call.Path),
+				slog.Int("status", call.StatusCode),
+				slog.Duration("latency", call.Latency),
+				slog.Int("attempt", call.Attempt),
+			}
+			if call.RequestID != "" {
+				attrs = append(attrs, slog.String("request_id", call.RequestID))
+			}
+			if call.Body != "" && logger.Enabled(ctx, slog.LevelDebug) {
+				attrs = append(attrs, slog.String("body", RedactForm(call.Body)))
+			}
+			if err != nil {
+				attrs = append(attrs, slog.String("error", err.Error()))
+				logger.LogAttrs(ctx, slog.LevelError, "stripe request failed", attrs...)
+			} else {
+				logger.LogAttrs(ctx, slog.LevelInfo, "stripe request", attrs...)
+			}
+			return resp, err
+		}
+	})
+}
+
+// DefaultLatencyBuckets are the bucket bounds used by NewLatencyHistogram when
+// none are given.
+var DefaultLatencyBuckets = []time.Duration{
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// LatencyHistogram is a Middleware that records the latency of every round
+// trip, grouped by endpoint, e.g. "POST charges".
+type LatencyHistogram struct {
+	buckets []time.Duration
+
+	mu     sync.Mutex
+	counts map[string][]uint64
+	sums   map[string]time.Duration
+}
+
+// NewLatencyHistogram returns a *LatencyHistogram whose buckets have the given
+// upper bounds. DefaultLatencyBuckets are used if none are given.
+func NewLatencyHistogram(buckets ...time.Duration) *LatencyHistogram {
+	if len(buckets) == 0 {
+		buckets = DefaultLatencyBuckets
+	}
+	buckets = append([]time.Duration(nil), buckets...)
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i] < buckets[j] })
+	return &LatencyHistogram{
+		buckets: buckets,
+		counts:  make(map[string][]uint64),
+		sums:    make(map[string]time.Duration),
+	}
+}
+
+func (h *LatencyHistogram) Wrap(next RoundTrip) RoundTrip {
+	return func(ctx context.Context, call *Call) ([]byte, error) {
+		resp, err := next(ctx, call)
+		h.Observe(call.endpoint(), call.Latency)
+		return resp, err
+	}
+}
+
+// Observe records a round trip to endpoint that took latency.
+func (h *LatencyHistogram) Observe(endpoint string, latency time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	counts, ok := h.counts[endpoint]
+	if !ok {
+		counts = make([]uint64, len(h.buckets)+1)
+		h.counts[endpoint] = counts
+	}
+	i := sort.Search(len(h.buckets), func(i int) bool { return latency <= h.buckets[i] })
+	counts[i]++
+	h.sums[endpoint] += latency
+}
+
+// Buckets returns the upper bounds of the histogram's buckets. Round trips
+// slower than the last bound are counted in an extra, final bucket.
+func (h *LatencyHistogram) Buckets() []time.Duration {
+	return append([]time.Duration(nil), h.buckets...)
+}
+
+// Counts returns the number of round trips to endpoint that fell in each bucket,
+// and the sum of their latencies.
+func (h *LatencyHistogram) Counts(endpoint string) (counts []uint64, sum time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	counts = make([]uint64, len(h.buckets)+1)
+	copy(counts, h.counts[endpoint])
+	return counts, h.sums[endpoint]
+}
+
+// Endpoints returns every endpoint the histogram has observed, sorted.
+func (h *LatencyHistogram) Endpoints() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	endpoints := make([]string, 0, len(h.counts))
+	for endpoint := range h.counts {
+		endpoints = append(endpoints, endpoint)
+	}
+	sort.Strings(endpoints)
+	return endpoints
+}
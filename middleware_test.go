@@ -0,0 +1,100 @@
+package stripe
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMiddlewareOrder(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if trace := strings.Join(r.Header.Values("X-Trace"), ","); trace != "outer,inner" {
+			t.Errorf("X-Trace is %q, expected %q", trace, "outer,inner")
+		}
+		w.Header().Set("Request-Id", "req_123")
+		w.Write([]byte(`{"id": "ch_123", "object": "charge"}`))
+	}))
+	defer server.Close()
+
+	var calls []*Call
+	trace := func(name string) Middleware {
+		return MiddlewareFunc(func(next RoundTrip) RoundTrip {
+			return func(ctx context.Context, call *Call) ([]byte, error) {
+				call.Header.Add("X-Trace", name)
+				resp, err := next(ctx, call)
+				calls = append(calls, call)
+				return resp, err
+			}
+		})
+	}
+	API := New("sk_test_key", WithBaseURL(server.URL+"/v1/"), WithMiddleware(trace("outer"), trace("inner")))
+	_, err := API.CreateCharge(&Token{ID: "tok_123"}, 500, "usd", "")
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	if len(calls) != 2 {
+		t.Fatalf("calls is %v, expected %v", len(calls), 2)
+	}
+	call := calls[0]
+	if call.Method != "POST" || call.Path != "charges" || call.StatusCode != 200 || call.RequestID != "req_123" {
+		t.Errorf("call is %+v, expected a 200 POST to charges with request ID req_123", call)
+	}
+	if !strings.Contains(call.Body, "amount=500") {
+		t.Errorf("call.Body is %q, expected it to contain %q", call.Body, "amount=500")
+	}
+}
+
+func TestLoggingMiddleware(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Request-Id", "req_123")
+		w.Write([]byte(`{"id": "tok_123", "object": "token"}`))
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	API := New("sk_test_key", WithBaseURL(server.URL+"/v1/"), WithMiddleware(LoggingMiddleware(logger)))
+	_, err := API.CreateToken(&Card{Number: "4242424242424242", ExpMonth: 3, ExpYear: 2030, CVC: "987"})
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	out := buf.String()
+	for _, want := range []string{"method=POST", "path=tokens", "status=200", "request_id=req_123", "body="} {
+		if !strings.Contains(out, want) {
+			t.Errorf("log is %q, expected it to contain %q", out, want)
+		}
+	}
+	if strings.Contains(out, "4242424242424242") || strings.Contains(out, "987") || strings.Contains(out, "sk_test_key") {
+		t.Errorf("log is %q, which exposes secrets", out)
+	}
+}
+
+func TestLatencyHistogram(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"count": 0, "data": []}`))
+	}))
+	defer server.Close()
+
+	histogram := NewLatencyHistogram()
+	API := New("sk_test_key", WithBaseURL(server.URL+"/v1/"), WithMiddleware(histogram))
+	for i := 0; i < 3; i++ {
+		if _, err := API.ListCharges(10, -1, ""); err != nil {
+			t.Fatalf("err = %v, want %v", err, nil)
+		}
+	}
+	if endpoints := histogram.Endpoints(); len(endpoints) != 1 || endpoints[0] != "GET charges" {
+		t.Fatalf("endpoints are %q, expected %q", endpoints, []string{"GET charges"})
+	}
+	counts, _ := histogram.Counts("GET charges")
+	var total uint64
+	for _, count := range counts {
+		total += count
+	}
+	if total != 3 {
+		t.Errorf("total is %v, expected %v", total, 3)
+	}
+}
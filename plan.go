@@ -1,63 +1,81 @@
 package stripe
 
 import (
+	"context"
 	"encoding/json"
 	"net/url"
 	"strconv"
 )
 
 type Plan struct {
-        Name      string    `json:"name"`
-        Object    string    `json:"object"`
-        ID        string    `json:"id"`
-        Interval  string    `json:"interval"`
-        Currency  string    `json:"currency"`
-        Amount    int       `json:"amount"`
-        TrialDays int       `json:"trial_period_days"`
-        Error     *RawError `json:"error"`
+	Name      string            `json:"name" form:"name"`
+	Object    string            `json:"object"`
+	ID        string            `json:"id" form:"id"`
+	Interval  string            `json:"interval" form:"interval"`
+	Currency  string            `json:"currency" form:"currency"`
+	Amount    int               `json:"amount" form:"amount"`
+	TrialDays int               `json:"trial_period_days" form:"trial_period_days,omitempty"`
+	Metadata  map[string]string `json:"metadata" form:"metadata,omitempty"`
+	Error     *RawError         `json:"error"`
+	rawObject
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (plan *Plan) UnmarshalJSON(data []byte) error {
+	type plainPlan Plan
+	return decodeRaw(data, (*plainPlan)(plan), &plan.rawObject)
+}
+
+// MarshalJSON implements json.Marshaler.
+func (plan Plan) MarshalJSON() ([]byte, error) {
+	type plainPlan Plan
+	return encodeRaw(plainPlan(plan), plan.rawObject)
 }
 
 func (plan *Plan) Values(values *url.Values) error {
-        if plan == nil {
-                // TODO: Throw error
-        }
-        if plan.ID == "" {
-                // TODO: Throw error
-        }
-        if plan.Name == "" {
-                // TODO: Throw error
-        }
-        if plan.Amount <= 0 {
-                // TODO: Throw error
-        }
-        if plan.Currency != "USD" {
-                // TODO: Throw error
-        }
-        if plan.Interval != "month" && plan.Interval != "year" {
-                // TODO: Throw error
-        }
-        if plan.TrialDays > 0 {
-                values.Set("trial_period_days", strconv.Itoa(plan.TrialDays))
-        }
-        values.Set("id", plan.ID)
-        values.Set("amount", strconv.Itoa(plan.Amount))
-        values.Set("currency", plan.Currency)
-        values.Set("interval", plan.Interval)
-        values.Set("name", plan.Name)
-        return nil
+	if plan == nil {
+		return invalid("plan", "no plan was provided")
+	}
+	if plan.ID == "" {
+		return invalid("id", "the plan has no ID")
+	}
+	if plan.Name == "" {
+		return invalid("name", "the plan has no name")
+	}
+	if plan.Amount <= 0 {
+		return invalid("amount", "must be positive")
+	}
+	if !validCurrency(plan.Currency) {
+		return invalid("currency", "must be a three-letter ISO currency code")
+	}
+	switch plan.Interval {
+	case "day", "week", "month", "year":
+	default:
+		return invalid("interval", `must be "day", "week", "month", or "year"`)
+	}
+	if plan.TrialDays < 0 {
+		return invalid("trial_period_days", "must not be negative")
+	}
+	err := validMetadata(plan.Metadata)
+	if err != nil {
+		return err
+	}
+	return encodeForm("", plan, values)
 }
 
 func (stripe *Stripe) CreatePlan(plan *Plan) (resp *Plan, err error) {
+	return stripe.CreatePlanCtx(context.Background(), plan)
+}
+
+// CreatePlanCtx is like CreatePlan, but uses ctx for the request to Stripe.
+func (stripe *Stripe) CreatePlanCtx(ctx context.Context, plan *Plan) (resp *Plan, err error) {
 	values := make(url.Values)
-        if plan == nil {
-                // TODO: Throw error
-        }
-        err = plan.Values(&values)
-        if err != nil {
-                return nil, err
-        }
+	err = plan.Values(&values)
+	if err != nil {
+		return nil, err
+	}
 	params := values.Encode()
-	r, err := stripe.request("POST", "plans", params)
+	r, err := stripe.request(ctx, "POST", "plans", params)
 	if err != nil {
 		return nil, err
 	}
@@ -66,13 +84,18 @@ func (stripe *Stripe) CreatePlan(plan *Plan) (resp *Plan, err error) {
 		return nil, err
 	}
 	if resp.Error != nil {
-		// TODO: Throw an error
+		return nil, resp.Error.typed(200)
 	}
 	return
 }
 
 func (stripe *Stripe) GetPlan(id string) (resp *Plan, err error) {
-	r, err := stripe.request("GET", "plans/"+id, "")
+	return stripe.GetPlanCtx(context.Background(), id)
+}
+
+// GetPlanCtx is like GetPlan, but uses ctx for the request to Stripe.
+func (stripe *Stripe) GetPlanCtx(ctx context.Context, id string) (resp *Plan, err error) {
+	r, err := stripe.request(ctx, "GET", "plans/"+id, "")
 	if err != nil {
 		return nil, err
 	}
@@ -81,16 +104,21 @@ func (stripe *Stripe) GetPlan(id string) (resp *Plan, err error) {
 		return nil, err
 	}
 	if resp.Error != nil {
-		// TODO: Throw an error
+		return nil, resp.Error.typed(200)
 	}
 	return
 }
 
 func (stripe *Stripe) UpdatePlan(id, name string) (resp *Plan, err error) {
+	return stripe.UpdatePlanCtx(context.Background(), id, name)
+}
+
+// UpdatePlanCtx is like UpdatePlan, but uses ctx for the request to Stripe.
+func (stripe *Stripe) UpdatePlanCtx(ctx context.Context, id, name string) (resp *Plan, err error) {
 	values := make(url.Values)
 	values.Set("name", name)
 	params := values.Encode()
-	r, err := stripe.request("POST", "plans/"+id, params)
+	r, err := stripe.request(ctx, "POST", "plans/"+id, params)
 	if err != nil {
 		return nil, err
 	}
@@ -99,32 +127,48 @@ func (stripe *Stripe) UpdatePlan(id, name string) (resp *Plan, err error) {
 		return nil, err
 	}
 	if resp.Error != nil {
-		// TODO: Throw an error
+		return nil, resp.Error.typed(200)
 	}
 	return
 }
 
 func (stripe *Stripe) DeletePlan(id string) (success bool, err error) {
-	r, err := stripe.request("DELETE", "plans/"+id, "")
+	return stripe.DeletePlanCtx(context.Background(), id)
+}
+
+// DeletePlanCtx is like DeletePlan, but uses ctx for the request to Stripe.
+func (stripe *Stripe) DeletePlanCtx(ctx context.Context, id string) (success bool, err error) {
+	r, err := stripe.request(ctx, "DELETE", "plans/"+id, "")
 	if err != nil {
 		return false, err
 	}
 	var raw struct {
-		Success bool      "deleted"
-		ID      string    "id"
-		Error   *RawError "error"
+		Success bool      `json:"deleted"`
+		ID      string    `json:"id"`
+		Error   *RawError `json:"error"`
 	}
 	err = json.Unmarshal(r, &raw)
 	if err != nil {
 		return false, err
 	}
 	if raw.Error != nil {
-		// TODO: throw an error
+		return false, raw.Error.typed(200)
 	}
-	return raw.Success, err
+	return raw.Success, nil
 }
 
 func (stripe *Stripe) ListPlans(count, offset int) (resp []*Plan, err error) {
+	return stripe.ListPlansCtx(context.Background(), count, offset)
+}
+
+// ListPlansCtx is like ListPlans, but uses ctx for the request to Stripe.
+func (stripe *Stripe) ListPlansCtx(ctx context.Context, count, offset int) (resp []*Plan, err error) {
+	resp, _, err = stripe.listPlans(ctx, count, offset)
+	return
+}
+
+// listPlans returns a page of Plans, along with the total number of Plans that match.
+func (stripe *Stripe) listPlans(ctx context.Context, count, offset int) (resp []*Plan, total int, err error) {
 	values := make(url.Values)
 	if count >= 0 {
 		values.Set("count", strconv.Itoa(count))
@@ -136,22 +180,33 @@ func (stripe *Stripe) ListPlans(count, offset int) (resp []*Plan, err error) {
 	if params != "" {
 		params = "?" + params
 	}
-	r, err := stripe.request("GET", "plans"+params, "")
+	r, err := stripe.request(ctx, "GET", "plans"+params, "")
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	var raw struct {
-		Count int "count"
-		Data  []*Plan
-		Error *RawError "error"
+		Count int       `json:"count"`
+		Data  []*Plan   `json:"data"`
+		Error *RawError `json:"error"`
 	}
 	err = json.Unmarshal(r, &raw)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	if raw.Error != nil {
-		// TODO: throw an error
+		return nil, 0, raw.Error.typed(200)
 	}
-	resp = raw.Data
-	return
+	return raw.Data, raw.Count, nil
+}
+
+// IterPlans returns an *Iter over all of your Plans.
+func (stripe *Stripe) IterPlans() *Iter[*Plan] {
+	return stripe.IterPlansCtx(context.Background())
+}
+
+// IterPlansCtx is like IterPlans, but uses ctx for every request to Stripe.
+func (stripe *Stripe) IterPlansCtx(ctx context.Context) *Iter[*Plan] {
+	return newIter(ctx, func(ctx context.Context, count, offset int) ([]*Plan, int, error) {
+		return stripe.listPlans(ctx, count, offset)
+	})
 }
@@ -1,23 +1,98 @@
 package stripe
 
 import (
-	"io/ioutil"
+	"errors"
+	"reflect"
 	"testing"
 )
 
-// TODO: TestCreatePlan
-// TODO: TestReadPlan
-// TODO: TestUpdatePlan
-// TODO: TestDeletePlan
+var GOLD = &Plan{
+	ID:        "gold",
+	Name:      "Gold",
+	Amount:    2000,
+	Currency:  "usd",
+	Interval:  "month",
+	TrialDays: 14,
+}
 
-func TestListPlans(t *testing.T) {
-	key, err := ioutil.ReadFile("key")
+func TestCreatePlan(t *testing.T) {
+	API, _ := emulate(t)
+	plan, err := API.CreatePlan(GOLD)
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	want := &Plan{Object: "plan", ID: GOLD.ID, Name: GOLD.Name, Amount: GOLD.Amount, Currency: GOLD.Currency, Interval: GOLD.Interval, TrialDays: GOLD.TrialDays, Metadata: map[string]string{}}
+	want.rawObject = plan.rawObject
+	if !reflect.DeepEqual(plan, want) {
+		t.Errorf("plan is %+v, expected the fields of %+v", plan, GOLD)
+	}
+	_, err = API.CreatePlan(GOLD)
+	var invalidErr *InvalidRequestError
+	if !errors.As(err, &invalidErr) {
+		t.Errorf("err = %v, want an *InvalidRequestError for a duplicate plan", err)
+	}
+}
+
+func TestGetPlan(t *testing.T) {
+	API, _ := emulate(t)
+	if _, err := API.CreatePlan(GOLD); err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	plan, err := API.GetPlan(GOLD.ID)
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	if plan.Amount != GOLD.Amount {
+		t.Errorf("Amount is %v, expected %v", plan.Amount, GOLD.Amount)
+	}
+	_, err = API.GetPlan("silver")
+	var notFound *NotFoundError
+	if !errors.As(err, &notFound) {
+		t.Errorf("err = %v, want a *NotFoundError", err)
+	}
+}
+
+func TestUpdatePlan(t *testing.T) {
+	API, _ := emulate(t)
+	if _, err := API.CreatePlan(GOLD); err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	plan, err := API.UpdatePlan(GOLD.ID, "Gold Special")
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	if plan.Name != "Gold Special" {
+		t.Errorf("Name is %v, expected %v", plan.Name, "Gold Special")
+	}
+}
+
+func TestDeletePlan(t *testing.T) {
+	API, _ := emulate(t)
+	if _, err := API.CreatePlan(GOLD); err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	success, err := API.DeletePlan(GOLD.ID)
 	if err != nil {
 		t.Fatalf("err = %v, want %v", err, nil)
 	}
-	API := New(string(key))
-	_, err = API.ListPlans()
+	if !success {
+		t.Errorf("success is %v, expected %v", success, true)
+	}
+	if _, err := API.GetPlan(GOLD.ID); err == nil {
+		t.Errorf("err = %v, want an error for a deleted plan", err)
+	}
+}
+
+func TestListPlans(t *testing.T) {
+	API, _ := emulate(t)
+	if _, err := API.CreatePlan(GOLD); err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	plans, err := API.ListPlans(-1, -1)
 	if err != nil {
 		t.Fatalf("err = %v, want %v", err, nil)
 	}
+	if len(plans) != 1 || plans[0].ID != GOLD.ID {
+		t.Errorf("plans is %v, expected only %v", plans, GOLD.ID)
+	}
 }
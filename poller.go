@@ -0,0 +1,219 @@
+package stripe
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// DefaultPollInterval is how often an EventPoller polls unless told otherwise.
+const DefaultPollInterval = 30 * time.Second
+
+// EventCursor records how far through the Event stream an EventPoller has got.
+type EventCursor struct {
+	// Created is the creation time of the newest Event processed.
+	Created int64 `json:"created"`
+	// IDs holds the IDs of the processed Events created at Created. Stripe
+	// timestamps have a resolution of one second, so these are needed to pick up
+	// where the poller left off without skipping or repeating Events.
+	IDs []string `json:"ids"`
+}
+
+func (cursor EventCursor) seen(event *Event) bool {
+	if event.Created < cursor.Created {
+		return true
+	}
+	if event.Created > cursor.Created {
+		return false
+	}
+	for _, id := range cursor.IDs {
+		if id == event.ID {
+			return true
+		}
+	}
+	return false
+}
+
+func (cursor EventCursor) advance(event *Event) EventCursor {
+	if event.Created > cursor.Created {
+		return EventCursor{Created: event.Created, IDs: []string{event.ID}}
+	}
+	ids := append(append([]string(nil), cursor.IDs...), event.ID)
+	return EventCursor{Created: cursor.Created, IDs: ids}
+}
+
+// CursorStore persists an EventPoller's EventCursor between runs.
+type CursorStore interface {
+	// Load returns the saved EventCursor, or the zero EventCursor if none has
+	// been saved yet.
+	Load(ctx context.Context) (EventCursor, error)
+	Save(ctx context.Context, cursor EventCursor) error
+}
+
+// MemoryCursorStore is a CursorStore that keeps its EventCursor in memory.
+type MemoryCursorStore struct {
+	mu     sync.Mutex
+	cursor EventCursor
+}
+
+func (store *MemoryCursorStore) Load(ctx context.Context) (EventCursor, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	return store.cursor, nil
+}
+
+func (store *MemoryCursorStore) Save(ctx context.Context, cursor EventCursor) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	store.cursor = cursor
+	return nil
+}
+
+// FileCursorStore is a CursorStore that keeps its EventCursor as JSON in the
+// file at Path. The file is replaced atomically on every Save, so a crash never
+// leaves it half-written.
+type FileCursorStore struct {
+	Path string
+}
+
+func (store *FileCursorStore) Load(ctx context.Context) (cursor EventCursor, err error) {
+	data, err := os.ReadFile(store.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return EventCursor{}, nil
+	}
+	if err != nil {
+		return EventCursor{}, err
+	}
+	err = json.Unmarshal(data, &cursor)
+	return cursor, err
+}
+
+func (store *FileCursorStore) Save(ctx context.Context, cursor EventCursor) error {
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(store.Path), filepath.Base(store.Path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), store.Path)
+}
+
+// EventPoller fetches new Events with ListEvents and passes them to a
+// Dispatcher, oldest first. It is an alternative to webhooks for environments
+// that can't receive them.
+//
+// The EventCursor is saved after every Event is handled, so a restarted poller
+// resumes where it left off. An Event whose handlers succeed just before a
+// crash, but before the cursor is saved, is dispatched again on restart; set
+// the Dispatcher's Store to have it handled only once.
+type EventPoller struct {
+	Stripe     *Stripe
+	Dispatcher *Dispatcher
+	Cursors    CursorStore
+	// Interval is the time between polls. DefaultPollInterval is used if it is zero.
+	Interval time.Duration
+	// Type, if set, limits polling to Events of that type, which may be a
+	// wildcard such as "charge.*".
+	Type string
+	// Start is where a poller without a saved EventCursor begins: only Events
+	// created at or after it are handled. If it is zero, the poller begins at
+	// the time of its first Poll, rather than working through every Event the
+	// account has ever had.
+	Start time.Time
+	// OnError, if set, is called with the error from any poll that fails. The
+	// poller carries on regardless, resuming from the last Event handled.
+	OnError func(err error)
+}
+
+// NewEventPoller returns an *EventPoller that fetches Events with stripe,
+// passes them to dispatcher, and saves its progress to cursors.
+func NewEventPoller(stripe *Stripe, dispatcher *Dispatcher, cursors CursorStore) *EventPoller {
+	return &EventPoller{Stripe: stripe, Dispatcher: dispatcher, Cursors: cursors}
+}
+
+// Run polls for Events every Interval until ctx is done, then returns ctx.Err().
+func (p *EventPoller) Run(ctx context.Context) error {
+	interval := p.Interval
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		err := p.Poll(ctx)
+		if err != nil && ctx.Err() == nil && p.OnError != nil {
+			p.OnError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll fetches every Event created since the last one handled and dispatches
+// them, oldest first. It stops at the first Event that fails to be handled, so
+// that Event is retried on the next Poll.
+func (p *EventPoller) Poll(ctx context.Context) error {
+	cursor, err := p.Cursors.Load(ctx)
+	if err != nil {
+		return err
+	}
+	if cursor.Created == 0 {
+		start := p.Start
+		if start.IsZero() {
+			start = time.Now()
+		}
+		// Saving the start straight away keeps it from moving on if the first
+		// Poll fails.
+		cursor = EventCursor{Created: start.Unix()}
+		err = p.Cursors.Save(ctx, cursor)
+		if err != nil {
+			return err
+		}
+	}
+	filter := &EventFilter{Type: p.Type, CreatedGTE: cursor.Created}
+	var events []*Event
+	it := p.Stripe.IterEventsCtx(ctx, filter)
+	for it.Next() {
+		events = append(events, it.Current())
+	}
+	if err = it.Err(); err != nil {
+		return err
+	}
+	// Stripe lists Events newest first.
+	for i := len(events) - 1; i >= 0; i-- {
+		event := events[i]
+		if cursor.seen(event) {
+			continue
+		}
+		err = p.Dispatcher.Dispatch(ctx, event)
+		if err != nil {
+			return err
+		}
+		cursor = cursor.advance(event)
+		err = p.Cursors.Save(ctx, cursor)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
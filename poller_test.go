@@ -0,0 +1,153 @@
+package stripe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// eventServer serves its events newest first, filtered by created[gte] and
+// paged by count and offset, as Stripe's events endpoint does.
+type eventServer struct {
+	mu     sync.Mutex
+	events []*Event
+}
+
+func (s *eventServer) add(id string, created int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, &Event{ID: id, Created: created, Type: "charge.succeeded"})
+}
+
+func (s *eventServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	query := r.URL.Query()
+	gte, _ := strconv.ParseInt(query.Get("created[gte]"), 10, 64)
+	count, _ := strconv.Atoi(query.Get("count"))
+	offset, _ := strconv.Atoi(query.Get("offset"))
+	var matched []*Event
+	for _, event := range s.events {
+		if event.Created >= gte {
+			matched = append(matched, event)
+		}
+	}
+	sort.SliceStable(matched, func(i, j int) bool { return matched[i].Created > matched[j].Created })
+	var data []string
+	for i := offset; i < offset+count && i < len(matched); i++ {
+		event := matched[i]
+		data = append(data, fmt.Sprintf(`{"id": %q, "object": "event", "type": %q, "created": %d, "data": {"object": {"id": "ch_%s", "object": "charge"}}}`, event.ID, event.Type, event.Created, event.ID))
+	}
+	fmt.Fprintf(w, `{"object": "list", "count": %d, "data": [%s]}`, len(matched), strings.Join(data, ","))
+}
+
+func TestEventPollerResumesWithoutGapsOrRepeats(t *testing.T) {
+	events := &eventServer{}
+	for i := 0; i < 150; i++ {
+		events.add(fmt.Sprintf("evt_%03d", i), int64(1000+i/2))
+	}
+	server := httptest.NewServer(events)
+	defer server.Close()
+
+	var handled []*Event
+	failing := true
+	dispatcher := NewDispatcher()
+	dispatcher.Handle("charge.succeeded", func(ctx context.Context, event *Event) error {
+		if failing && event.Created == 1050 {
+			return errors.New("handler failed")
+		}
+		handled = append(handled, event)
+		return nil
+	})
+	cursors := &FileCursorStore{Path: filepath.Join(t.TempDir(), "cursor.json")}
+	API := New("sk_test_key", WithBaseURL(server.URL+"/v1/"))
+	newPoller := func() *EventPoller {
+		poller := NewEventPoller(API, dispatcher, cursors)
+		poller.Start = time.Unix(1000, 0)
+		return poller
+	}
+
+	if err := newPoller().Poll(context.Background()); err == nil {
+		t.Fatalf("err = %v, want an error", err)
+	}
+	if len(handled) != 100 {
+		t.Fatalf("handled %v events, expected %v", len(handled), 100)
+	}
+
+	// A new poller, as after a restart, picks up at the failed event.
+	failing = false
+	events.add("evt_150", 1075)
+	events.add("evt_151", 1076)
+	if err := newPoller().Poll(context.Background()); err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	if err := newPoller().Poll(context.Background()); err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	if len(handled) != 152 {
+		t.Fatalf("handled %v events, expected %v", len(handled), 152)
+	}
+	seen := make(map[string]bool)
+	for i, event := range handled {
+		if seen[event.ID] {
+			t.Errorf("%v was handled twice", event.ID)
+		}
+		seen[event.ID] = true
+		if i > 0 && event.Created < handled[i-1].Created {
+			t.Errorf("%v was handled after the newer %v", event.ID, handled[i-1].ID)
+		}
+	}
+}
+
+func TestEventPollerRun(t *testing.T) {
+	events := &eventServer{}
+	events.add("evt_1", 1000)
+	server := httptest.NewServer(events)
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	dispatcher := NewDispatcher()
+	dispatcher.Handle("charge.succeeded", func(ctx context.Context, event *Event) error {
+		cancel()
+		return nil
+	})
+	poller := NewEventPoller(New("sk_test_key", WithBaseURL(server.URL+"/v1/")), dispatcher, &MemoryCursorStore{})
+	poller.Start = time.Unix(1000, 0)
+	if err := poller.Run(ctx); err != context.Canceled {
+		t.Errorf("err = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestEventPollerStartsAtFirstPoll(t *testing.T) {
+	events := &eventServer{}
+	events.add("evt_old", 1000)
+	server := httptest.NewServer(events)
+	defer server.Close()
+
+	var handled []string
+	dispatcher := NewDispatcher()
+	dispatcher.Handle("charge.succeeded", func(ctx context.Context, event *Event) error {
+		handled = append(handled, event.ID)
+		return nil
+	})
+	poller := NewEventPoller(New("sk_test_key", WithBaseURL(server.URL+"/v1/")), dispatcher, &MemoryCursorStore{})
+	if err := poller.Poll(context.Background()); err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	events.add("evt_new", time.Now().Unix()+1)
+	if err := poller.Poll(context.Background()); err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	if len(handled) != 1 || handled[0] != "evt_new" {
+		t.Errorf("handled %v, expected only %v", handled, "evt_new")
+	}
+}
@@ -0,0 +1,97 @@
+package stripe
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+)
+
+// rawObject is embedded in every object this package decodes from the Stripe
+// API. It keeps the JSON the object was decoded from, so that Stripe adding a
+// field to its objects doesn't mean losing it: the field can be read from
+// RawJSON before this package models it, and is written back out when the
+// object is re-marshaled.
+//
+// The JSON is kept as strings, so the objects embedding a rawObject can still
+// be compared with ==.
+type rawObject struct {
+	json string
+	// encoded is what the object marshaled as when it was decoded, which tells
+	// which of its fields have changed since.
+	encoded string
+}
+
+// RawJSON returns the JSON the object was decoded from, including any fields
+// this package doesn't model. It's nil for an object that wasn't decoded from
+// JSON, such as one built to be sent to Stripe.
+func (raw rawObject) RawJSON() json.RawMessage {
+	if raw.json == "" {
+		return nil
+	}
+	return json.RawMessage(raw.json)
+}
+
+// decodeRaw decodes data into v, a pointer to a struct without an UnmarshalJSON
+// method of its own, and keeps data in *raw.
+func decodeRaw(data []byte, v interface{}, raw *rawObject) error {
+	err := json.Unmarshal(data, v)
+	if err != nil {
+		return err
+	}
+	return raw.keep(data, reflect.ValueOf(v).Elem().Interface())
+}
+
+// keep stores data, which v, a struct without a MarshalJSON method of its own,
+// was decoded from, in *raw, unless it's null.
+func (raw *rawObject) keep(data []byte, v interface{}) error {
+	*raw = rawObject{}
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		return nil
+	}
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	raw.json = string(data)
+	raw.encoded = string(encoded)
+	return nil
+}
+
+// encodeRaw marshals v, a struct without a MarshalJSON method of its own. If v
+// was decoded from the JSON kept in raw, it's marshaled as that JSON with only
+// the fields v has changed since replaced, so fields v doesn't model, fields
+// the JSON left out, and nulls v can only hold as zero values are all written
+// back as they were.
+func encodeRaw(v interface{}, raw rawObject) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil || raw.json == "" {
+		return data, err
+	}
+	if string(data) == raw.encoded {
+		return []byte(raw.json), nil
+	}
+	var fields, was, is map[string]json.RawMessage
+	err = json.Unmarshal([]byte(raw.json), &fields)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal([]byte(raw.encoded), &was)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(data, &is)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range is {
+		if previous, ok := was[key]; !ok || !bytes.Equal(value, previous) {
+			fields[key] = value
+		}
+	}
+	for key := range was {
+		if _, ok := is[key]; !ok {
+			delete(fields, key)
+		}
+	}
+	return json.Marshal(fields)
+}
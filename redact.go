@@ -0,0 +1,88 @@
+package stripe
+
+import (
+	"encoding/base64"
+	"net/url"
+	"strings"
+)
+
+const mask = "••••"
+
+// MaskCardNumber formats a card number for display, hiding every digit but the
+// last four, e.g. "•••• 4242". Spaces and dashes in number are ignored.
+func MaskCardNumber(number string) string {
+	digits := strings.NewReplacer(" ", "", "-", "").Replace(number)
+	if digits == "" {
+		return ""
+	}
+	if len(digits) <= 4 {
+		return mask
+	}
+	return mask + " " + digits[len(digits)-4:]
+}
+
+// RedactKey hides all but the prefix and last four characters of an API key,
+// e.g. "sk_test_••••4242", so the key can be identified but not used.
+func RedactKey(key string) string {
+	if key == "" {
+		return ""
+	}
+	prefix := ""
+	if i := strings.LastIndex(key, "_"); i >= 0 && i < 8 {
+		prefix, key = key[:i+1], key[i+1:]
+	}
+	if len(key) <= 8 {
+		return prefix + mask
+	}
+	return prefix + mask + key[len(key)-4:]
+}
+
+// RedactForm returns body, a URL-encoded request body, with card numbers and
+// security codes masked so it's safe to log.
+func RedactForm(body string) string {
+	values, err := url.ParseQuery(body)
+	if err != nil {
+		return mask
+	}
+	redacted := false
+	for key, vals := range values {
+		for i, val := range vals {
+			switch {
+			case key == "number" || strings.HasSuffix(key, "[number]"):
+				vals[i] = MaskCardNumber(val)
+			case key == "cvc" || strings.HasSuffix(key, "[cvc]"):
+				vals[i] = mask
+			default:
+				continue
+			}
+			redacted = true
+		}
+	}
+	if !redacted {
+		return body
+	}
+	return values.Encode()
+}
+
+// redactAuthorization masks the API key in an HTTP Authorization header.
+func redactAuthorization(header string) string {
+	if header == "" {
+		return ""
+	}
+	scheme, credentials, ok := strings.Cut(header, " ")
+	if !ok {
+		return mask
+	}
+	switch strings.ToLower(scheme) {
+	case "basic":
+		decoded, err := base64.StdEncoding.DecodeString(credentials)
+		if err != nil {
+			return scheme + " " + mask
+		}
+		key, _, _ := strings.Cut(string(decoded), ":")
+		return scheme + " " + RedactKey(key)
+	case "bearer":
+		return scheme + " " + RedactKey(credentials)
+	}
+	return scheme + " " + mask
+}
@@ -0,0 +1,122 @@
+package stripe
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMaskCardNumber(t *testing.T) {
+	tests := map[string]string{
+		"4242424242424242":    "•••• 4242",
+		"4242-4242-4242-4242": "•••• 4242",
+		"42":                  "••••",
+		"":                    "",
+	}
+	for number, want := range tests {
+		if got := MaskCardNumber(number); got != want {
+			t.Errorf("MaskCardNumber(%q) is %q, expected %q", number, got, want)
+		}
+	}
+}
+
+func TestRedactKey(t *testing.T) {
+	tests := map[string]string{
+		"sk_test_4eC39HqLyjWDarjtT1zdp7dc": "sk_test_••••p7dc",
+		"sk_live_short":                    "sk_live_••••",
+		"abcdefghijklmnop":                 "••••mnop",
+	}
+	for key, want := range tests {
+		if got := RedactKey(key); got != want {
+			t.Errorf("RedactKey(%q) is %q, expected %q", key, got, want)
+		}
+	}
+}
+
+func TestRedactForm(t *testing.T) {
+	for _, body := range []string{
+		"amount=500&card%5Bcvc%5D=123&card%5Bnumber%5D=4242424242424242&currency=usd",
+		"amount=500&cvc=123&number=4242424242424242&currency=usd",
+	} {
+		redacted := RedactForm(body)
+		if strings.Contains(redacted, "4242424242424242") || strings.Contains(redacted, "123") {
+			t.Errorf("RedactForm(%q) is %q, which exposes card details", body, redacted)
+		}
+		if !strings.Contains(redacted, "amount=500") {
+			t.Errorf("RedactForm(%q) is %q, which lost the amount", body, redacted)
+		}
+	}
+}
+
+func TestCardFormattingIsRedacted(t *testing.T) {
+	card := &Card{Number: "4242424242424242", CVC: "987", ExpMonth: 3, ExpYear: 2030}
+	for _, format := range []string{"%v", "%+v", "%s", "%#v"} {
+		for _, value := range []interface{}{card, *card} {
+			out := fmt.Sprintf(format, value)
+			if strings.Contains(out, "4242424242424242") || strings.Contains(out, "987") {
+				t.Errorf("Sprintf(%q) is %q, which exposes card details", format, out)
+			}
+		}
+	}
+}
+
+func TestLoggingIsRedacted(t *testing.T) {
+	card := &Card{Number: "4242424242424242", CVC: "987", ExpMonth: 3, ExpYear: 2030}
+	token := &Token{ID: "tok_123", Card: card}
+	params := &ChargeParams{Amount: 500, Currency: "usd", Source: card}
+	var buf bytes.Buffer
+	for _, handler := range []slog.Handler{slog.NewJSONHandler(&buf, nil), slog.NewTextHandler(&buf, nil)} {
+		slog.New(handler).Info("charging", "card", card, "token", token, "token_value", *token, "params", params)
+	}
+	if out := buf.String(); strings.Contains(out, "4242424242424242") || strings.Contains(out, "987") {
+		t.Errorf("logged %q, which exposes card details", out)
+	}
+	if card.Number != "4242424242424242" || card.CVC != "987" {
+		t.Errorf("card is %#v after logging, expected it to be unchanged", card)
+	}
+}
+
+func TestUnauthorizedErrorIsRedacted(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(401)
+		w.Write([]byte(`{"error": {"type": "invalid_request_error", "message": "Invalid API Key provided."}}`))
+	}))
+	defer server.Close()
+
+	key := "sk_test_4eC39HqLyjWDarjtT1zdp7dc"
+	API := New(key, WithBaseURL(server.URL+"/v1/"))
+	_, err := API.CreateCharge(&Card{Number: "4242424242424242", ExpMonth: 3, ExpYear: 2030, CVC: "987"}, 500, "usd", "")
+	var authErr *UnauthorizedError
+	if !errors.As(err, &authErr) {
+		t.Fatalf("err = %#v, want an *UnauthorizedError", err)
+	}
+	if strings.Contains(err.Error(), key) {
+		t.Errorf("err.Error() is %q, which exposes the API key", err.Error())
+	}
+	if authErr.Auth != "Basic sk_test_••••p7dc" {
+		t.Errorf("Auth is %q, expected %q", authErr.Auth, "Basic sk_test_••••p7dc")
+	}
+}
+
+func TestBadRequestErrorIsRedacted(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(400)
+	}))
+	defer server.Close()
+
+	key := "sk_test_4eC39HqLyjWDarjtT1zdp7dc"
+	API := New(key, WithBaseURL(server.URL+"/v1/"))
+	_, err := API.CreateToken(&Card{Number: "4242424242424242", ExpMonth: 3, ExpYear: 2030, CVC: "987"})
+	if _, ok := err.(*BadRequestError); !ok {
+		t.Fatalf("err = %#v, want a *BadRequestError", err)
+	}
+	msg := err.Error()
+	if strings.Contains(msg, key) || strings.Contains(msg, "4242424242424242") || strings.Contains(msg, "Authorization") {
+		t.Errorf("err.Error() is %q, which exposes secrets", msg)
+	}
+}
@@ -0,0 +1,172 @@
+package stripe
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultRefetchConcurrency is the default number of Events a RefetchVerifier
+	// fetches at once.
+	DefaultRefetchConcurrency = 4
+	// DefaultRefetchCacheTTL is the default time a RefetchVerifier remembers an
+	// Event it fetched.
+	DefaultRefetchCacheTTL = 10 * time.Minute
+	// DefaultRefetchCacheSize is the default number of Events a RefetchVerifier remembers.
+	DefaultRefetchCacheSize = 1000
+)
+
+// UnavailableError is returned by an EventVerifier that could not decide
+// whether an Event is authentic, e.g. because Stripe could not be reached.
+// WebhookHandler responds 503 to these, so that Stripe delivers the Event again.
+type UnavailableError struct {
+	Err error
+}
+
+func (err *UnavailableError) Error() string {
+	return fmt.Sprintf("Could not verify event: %v", err.Err)
+}
+
+func (err *UnavailableError) Unwrap() error {
+	return err.Err
+}
+
+// RefetchVerifier authenticates webhooks by ignoring everything in them but
+// the Event's ID, and fetching the Event with that ID from the Stripe API. Only
+// data retrieved from Stripe is trusted, so no signing secret is needed.
+//
+// Events it has fetched recently are cached, and the number of fetches in
+// flight at once is bounded, so bursts of webhooks don't flood the API. Every
+// delivery is given its own copy of a cached Event, so handlers are free to
+// modify it.
+type RefetchVerifier struct {
+	Stripe *Stripe
+	// MaxConcurrent bounds how many Events are fetched at once.
+	// DefaultRefetchConcurrency is used if it is zero.
+	MaxConcurrent int
+	// CacheTTL is how long a fetched Event is remembered.
+	// DefaultRefetchCacheTTL is used if it is zero.
+	CacheTTL time.Duration
+	// CacheSize is the most Events remembered at once.
+	// DefaultRefetchCacheSize is used if it is zero.
+	CacheSize int
+
+	once  sync.Once
+	slots chan struct{}
+
+	mu    sync.Mutex
+	cache map[string]cachedEvent
+	order []string
+	now   func() time.Time
+}
+
+// cachedEvent keeps an Event as JSON, so that every delivery of it is given
+// its own copy to modify.
+type cachedEvent struct {
+	data    []byte
+	expires time.Time
+}
+
+// NewRefetchVerifier returns a *RefetchVerifier that fetches Events with stripe.
+func NewRefetchVerifier(stripe *Stripe) *RefetchVerifier {
+	return &RefetchVerifier{Stripe: stripe}
+}
+
+func (v *RefetchVerifier) init() {
+	v.once.Do(func() {
+		limit := v.MaxConcurrent
+		if limit <= 0 {
+			limit = DefaultRefetchConcurrency
+		}
+		v.slots = make(chan struct{}, limit)
+		v.cache = make(map[string]cachedEvent)
+		if v.now == nil {
+			v.now = time.Now
+		}
+	})
+}
+
+// VerifyEvent satisfies EventVerifier.
+func (v *RefetchVerifier) VerifyEvent(r *http.Request, payload []byte) (*Event, error) {
+	v.init()
+	var incoming struct {
+		ID string `json:"id"`
+	}
+	err := json.Unmarshal(payload, &incoming)
+	if err != nil {
+		return nil, err
+	}
+	if incoming.ID == "" {
+		return nil, invalid("id", "the event has no ID")
+	}
+	if event := v.cached(incoming.ID); event != nil {
+		return event, nil
+	}
+
+	ctx := r.Context()
+	select {
+	case v.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, &UnavailableError{ctx.Err()}
+	}
+	defer func() { <-v.slots }()
+
+	event, err := v.Stripe.GetEventCtx(ctx, incoming.ID)
+	if err != nil {
+		var notFound *NotFoundError
+		var badRequest *InvalidRequestError
+		if errors.As(err, &notFound) || errors.As(err, &badRequest) {
+			return nil, err
+		}
+		return nil, &UnavailableError{err}
+	}
+	err = v.store(event)
+	if err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+func (v *RefetchVerifier) cached(id string) *Event {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	entry, ok := v.cache[id]
+	if !ok || v.now().After(entry.expires) {
+		return nil
+	}
+	var event Event
+	if json.Unmarshal(entry.data, &event) != nil {
+		return nil
+	}
+	return &event
+}
+
+func (v *RefetchVerifier) store(event *Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	ttl := v.CacheTTL
+	if ttl <= 0 {
+		ttl = DefaultRefetchCacheTTL
+	}
+	size := v.CacheSize
+	if size <= 0 {
+		size = DefaultRefetchCacheSize
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if _, ok := v.cache[event.ID]; !ok {
+		v.order = append(v.order, event.ID)
+	}
+	v.cache[event.ID] = cachedEvent{data: data, expires: v.now().Add(ttl)}
+	for len(v.order) > size {
+		delete(v.cache, v.order[0])
+		v.order = v.order[1:]
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package stripe
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRefetchVerifierTrustsOnlyFetchedData(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		switch r.URL.Path {
+		case "/v1/events/evt_123":
+			w.Write([]byte(chargeSucceeded))
+		case "/v1/events/evt_down":
+			w.WriteHeader(503)
+		default:
+			w.WriteHeader(404)
+			w.Write([]byte(`{"error": {"type": "invalid_request_error", "message": "No such event"}}`))
+		}
+	}))
+	defer server.Close()
+
+	dispatcher := NewDispatcher()
+	var amounts []int
+	dispatcher.HandleCharge("charge.succeeded", func(ctx context.Context, event *Event, charge *Charge) error {
+		amounts = append(amounts, charge.Amount)
+		// Changing the Event mustn't change what later deliveries of it get.
+		charge.Amount = 0
+		return nil
+	})
+	handler := NewWebhookHandler(dispatcher)
+	handler.Verifier = NewRefetchVerifier(New("sk_test_key", WithBaseURL(server.URL+"/v1/")))
+
+	forged := strings.Replace(chargeSucceeded, `"amount": 500`, `"amount": 999999`, 1)
+	for i := 0; i < 2; i++ {
+		if recorder := postEvent(handler, forged); recorder.Code != 200 {
+			t.Fatalf("status is %v, expected %v", recorder.Code, 200)
+		}
+	}
+	if len(amounts) != 2 || amounts[0] != 500 || amounts[1] != 500 {
+		t.Errorf("amounts are %v, expected %v", amounts, []int{500, 500})
+	}
+	if requests != 1 {
+		t.Errorf("requests is %v, expected %v, as the second delivery should be cached", requests, 1)
+	}
+
+	if recorder := postEvent(handler, `{"id": "evt_forged", "type": "charge.succeeded"}`); recorder.Code != 400 {
+		t.Errorf("unknown event: status is %v, expected %v", recorder.Code, 400)
+	}
+	if recorder := postEvent(handler, `{"id": "evt_down", "type": "charge.succeeded"}`); recorder.Code != 503 {
+		t.Errorf("unavailable: status is %v, expected %v", recorder.Code, 503)
+	}
+}
+
+func TestRefetchVerifierBoundsConcurrency(t *testing.T) {
+	var inFlight, maxInFlight int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		for {
+			max := atomic.LoadInt32(&maxInFlight)
+			if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt32(&inFlight, -1)
+		id := strings.TrimPrefix(r.URL.Path, "/v1/events/")
+		w.Write([]byte(`{"id": "` + id + `", "object": "event", "type": "ping", "data": {"object": {}}}`))
+	}))
+	defer server.Close()
+
+	verifier := NewRefetchVerifier(New("sk_test_key", WithBaseURL(server.URL+"/v1/")))
+	verifier.MaxConcurrent = 2
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			req := httptest.NewRequest("POST", "/webhook", nil)
+			if _, err := verifier.VerifyEvent(req, []byte(`{"id": "`+id+`"}`)); err != nil {
+				t.Errorf("err = %v, want %v", err, nil)
+			}
+		}("evt_" + string(rune('a'+i)))
+	}
+	wg.Wait()
+	if maxInFlight > 2 {
+		t.Errorf("max in flight is %v, expected at most %v", maxInFlight, 2)
+	}
+}
+
+func TestRefetchVerifierCacheEviction(t *testing.T) {
+	verifier := &RefetchVerifier{CacheSize: 2, CacheTTL: time.Minute}
+	now := time.Unix(1360000000, 0)
+	verifier.now = func() time.Time { return now }
+	verifier.init()
+	for _, id := range []string{"evt_1", "evt_2", "evt_3"} {
+		verifier.store(&Event{ID: id})
+	}
+	if verifier.cached("evt_1") != nil || verifier.cached("evt_3") == nil {
+		t.Errorf("expected the oldest event to be evicted")
+	}
+	now = now.Add(2 * time.Minute)
+	if verifier.cached("evt_3") != nil {
+		t.Errorf("expected evt_3 to have expired")
+	}
+}
@@ -0,0 +1,183 @@
+package stripe
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"strconv"
+)
+
+// The reasons a Refund can be given for.
+const (
+	RefundReasonDuplicate           = "duplicate"
+	RefundReasonFraudulent          = "fraudulent"
+	RefundReasonRequestedByCustomer = "requested_by_customer"
+)
+
+// Refund represents the return of all or part of a Charge, according to the Stripe API.
+type Refund struct {
+	ID       string            `json:"id"`
+	Object   string            `json:"object"` // Should always be "refund"
+	Amount   int               `json:"amount"`
+	Currency string            `json:"currency"`
+	Created  int64             `json:"created"`
+	ChargeID string            `json:"charge"`
+	Reason   string            `json:"reason"` // One of the RefundReason constants, or empty
+	Metadata map[string]string `json:"metadata"`
+	Error    *RawError         `json:"error"`
+	rawObject
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (refund *Refund) UnmarshalJSON(data []byte) error {
+	type plainRefund Refund
+	return decodeRaw(data, (*plainRefund)(refund), &refund.rawObject)
+}
+
+// MarshalJSON implements json.Marshaler.
+func (refund Refund) MarshalJSON() ([]byte, error) {
+	type plainRefund Refund
+	return encodeRaw(plainRefund(refund), refund.rawObject)
+}
+
+// RefundList is the list of a Charge's Refunds embedded in the Charge, newest
+// first. If Count is greater than len(Data), ListRefunds or IterRefunds return
+// the rest.
+type RefundList struct {
+	Object string    `json:"object"` // Should always be "list"
+	URL    string    `json:"url"`
+	Count  int       `json:"count"` // The total number of Refunds
+	Data   []*Refund `json:"data"`
+}
+
+// CreateRefund refunds amount of the Charge with an ID of chargeID. If amount is
+// 0, all of the Charge that hasn't been refunded yet is. Reason, which is
+// optional, must be one of the RefundReason constants; metadata is optional.
+func (stripe *Stripe) CreateRefund(chargeID string, amount int, reason string, metadata map[string]string) (resp *Refund, err error) {
+	return stripe.CreateRefundCtx(context.Background(), chargeID, amount, reason, metadata)
+}
+
+// CreateRefundCtx is like CreateRefund, but uses ctx for the request to Stripe.
+func (stripe *Stripe) CreateRefundCtx(ctx context.Context, chargeID string, amount int, reason string, metadata map[string]string) (resp *Refund, err error) {
+	if chargeID == "" {
+		return nil, invalid("charge", "no charge ID was provided")
+	}
+	if amount < 0 {
+		return nil, invalid("amount", "must not be negative")
+	}
+	switch reason {
+	case "", RefundReasonDuplicate, RefundReasonFraudulent, RefundReasonRequestedByCustomer:
+	default:
+		return nil, invalid("reason", `must be "duplicate", "fraudulent", or "requested_by_customer"`)
+	}
+	values := make(url.Values)
+	if amount > 0 {
+		values.Set("amount", strconv.Itoa(amount))
+	}
+	if reason != "" {
+		values.Set("reason", reason)
+	}
+	err = metadataValues(metadata, &values)
+	if err != nil {
+		return nil, err
+	}
+	r, err := stripe.request(ctx, "POST", "charges/"+chargeID+"/refunds", values.Encode())
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(r, &resp)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != nil {
+		return nil, resp.Error.typed(200)
+	}
+	return
+}
+
+// GetRefund retrieves the Refund with an ID of id of the Charge with an ID of chargeID.
+func (stripe *Stripe) GetRefund(chargeID, id string) (resp *Refund, err error) {
+	return stripe.GetRefundCtx(context.Background(), chargeID, id)
+}
+
+// GetRefundCtx is like GetRefund, but uses ctx for the request to Stripe.
+func (stripe *Stripe) GetRefundCtx(ctx context.Context, chargeID, id string) (resp *Refund, err error) {
+	if chargeID == "" {
+		return nil, invalid("charge", "no charge ID was provided")
+	}
+	if id == "" {
+		return nil, invalid("id", "no ID was provided")
+	}
+	r, err := stripe.request(ctx, "GET", "charges/"+chargeID+"/refunds/"+id, "")
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(r, &resp)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != nil {
+		return nil, resp.Error.typed(200)
+	}
+	return
+}
+
+// ListRefunds returns the Refunds of the Charge with an ID of chargeID, newest first.
+//
+// Pass -1 to count to use the Stripe default (10). Pass -1 to offset to use the Stripe default (0).
+func (stripe *Stripe) ListRefunds(chargeID string, count, offset int) (resp []*Refund, err error) {
+	return stripe.ListRefundsCtx(context.Background(), chargeID, count, offset)
+}
+
+// ListRefundsCtx is like ListRefunds, but uses ctx for the request to Stripe.
+func (stripe *Stripe) ListRefundsCtx(ctx context.Context, chargeID string, count, offset int) (resp []*Refund, err error) {
+	resp, _, err = stripe.listRefunds(ctx, chargeID, count, offset)
+	return
+}
+
+// listRefunds returns a page of a Charge's Refunds, along with the total number of its Refunds.
+func (stripe *Stripe) listRefunds(ctx context.Context, chargeID string, count, offset int) (resp []*Refund, total int, err error) {
+	if chargeID == "" {
+		return nil, 0, invalid("charge", "no charge ID was provided")
+	}
+	values := make(url.Values)
+	if count >= 0 {
+		values.Set("count", strconv.Itoa(count))
+	}
+	if offset >= 0 {
+		values.Set("offset", strconv.Itoa(offset))
+	}
+	params := values.Encode()
+	if params != "" {
+		params = "?" + params
+	}
+	r, err := stripe.request(ctx, "GET", "charges/"+chargeID+"/refunds"+params, "")
+	if err != nil {
+		return nil, 0, err
+	}
+	var raw struct {
+		Count int       `json:"count"`
+		Data  []*Refund `json:"data"`
+		Error *RawError `json:"error"`
+	}
+	err = json.Unmarshal(r, &raw)
+	if err != nil {
+		return nil, 0, err
+	}
+	if raw.Error != nil {
+		return nil, 0, raw.Error.typed(200)
+	}
+	return raw.Data, raw.Count, nil
+}
+
+// IterRefunds returns an *Iter over all of the Refunds of the Charge with an ID of chargeID.
+func (stripe *Stripe) IterRefunds(chargeID string) *Iter[*Refund] {
+	return stripe.IterRefundsCtx(context.Background(), chargeID)
+}
+
+// IterRefundsCtx is like IterRefunds, but uses ctx for every request to Stripe.
+func (stripe *Stripe) IterRefundsCtx(ctx context.Context, chargeID string) *Iter[*Refund] {
+	return newIter(ctx, func(ctx context.Context, count, offset int) ([]*Refund, int, error) {
+		return stripe.listRefunds(ctx, chargeID, count, offset)
+	})
+}
@@ -0,0 +1,134 @@
+package stripe
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCreateRefund(t *testing.T) {
+	API, _ := emulate(t)
+	charge, err := API.CreateCharge(VALID, 2000, "usd", "")
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	first, err := API.CreateRefund(charge.ID, 500, RefundReasonDuplicate, map[string]string{"ticket": "42"})
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	if first.Amount != 500 || first.ChargeID != charge.ID || first.Reason != RefundReasonDuplicate {
+		t.Errorf("refund is %+v, expected %v of %v for %v", first, 500, charge.ID, RefundReasonDuplicate)
+	}
+	if first.Metadata["ticket"] != "42" {
+		t.Errorf("Metadata is %v, expected %v", first.Metadata, map[string]string{"ticket": "42"})
+	}
+	last, err := API.CreateRefund(charge.ID, 0, "", nil)
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	if last.Amount != 1500 || last.Reason != "" {
+		t.Errorf("refund is %+v, expected the remaining %v without a reason", last, 1500)
+	}
+	_, err = API.CreateRefund(charge.ID, 0, "", nil)
+	var invalidErr *InvalidRequestError
+	if !errors.As(err, &invalidErr) {
+		t.Errorf("err = %v, want an *InvalidRequestError for a refunded charge", err)
+	}
+
+	refund, err := API.GetRefund(charge.ID, first.ID)
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	if refund.ID != first.ID || refund.Amount != first.Amount {
+		t.Errorf("refund is %+v, expected %+v", refund, first)
+	}
+	refunds, err := API.ListRefunds(charge.ID, -1, -1)
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	if len(refunds) != 2 || refunds[0].ID != last.ID || refunds[1].ID != first.ID {
+		t.Errorf("refunds is %v, expected %v and %v", refunds, last.ID, first.ID)
+	}
+	seen := 0
+	for refund, err := range API.IterRefunds(charge.ID).All() {
+		if err != nil {
+			t.Fatalf("err = %v, want %v", err, nil)
+		}
+		if refund.ChargeID != charge.ID {
+			t.Errorf("ChargeID is %v, expected %v", refund.ChargeID, charge.ID)
+		}
+		seen++
+	}
+	if seen != 2 {
+		t.Errorf("seen is %v, expected %v", seen, 2)
+	}
+
+	charge, err = API.GetCharge(charge.ID)
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	if !charge.Refunded || charge.AmountRefunded != 2000 {
+		t.Errorf("Refunded is %v and AmountRefunded is %v, expected %v and %v", charge.Refunded, charge.AmountRefunded, true, 2000)
+	}
+	if charge.Refunds.Count != 2 || len(charge.Refunds.Data) != 2 || charge.Refunds.Data[0].ID != last.ID {
+		t.Errorf("Refunds is %+v, expected %v and %v", charge.Refunds, last.ID, first.ID)
+	}
+}
+
+func TestRefundsOfOtherCharges(t *testing.T) {
+	API, _ := emulate(t)
+	charge, err := API.CreateCharge(VALID, 2000, "usd", "")
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	other, err := API.CreateCharge(VALID, 2000, "usd", "")
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	// RefundCharge creates a Refund as well.
+	charge, err = API.RefundCharge(charge.ID, 500)
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	if len(charge.Refunds.Data) != 1 {
+		t.Fatalf("Refunds is %+v, expected one Refund", charge.Refunds)
+	}
+	refunds, err := API.ListRefunds(other.ID, -1, -1)
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	if len(refunds) != 0 {
+		t.Errorf("refunds is %v, expected none", refunds)
+	}
+	_, err = API.GetRefund(other.ID, charge.Refunds.Data[0].ID)
+	var notFound *NotFoundError
+	if !errors.As(err, &notFound) {
+		t.Errorf("err = %v, want a *NotFoundError for another charge's refund", err)
+	}
+}
+
+func TestCreateRefundValidation(t *testing.T) {
+	API := New("sk_test_key")
+	tests := []struct {
+		chargeID string
+		amount   int
+		reason   string
+		metadata map[string]string
+		field    string
+	}{
+		{"", 500, "", nil, "charge"},
+		{"ch_123", -1, "", nil, "amount"},
+		{"ch_123", 500, "changed_mind", nil, "reason"},
+		{"ch_123", 500, "", map[string]string{"": "empty"}, "metadata"},
+	}
+	for _, test := range tests {
+		_, err := API.CreateRefund(test.chargeID, test.amount, test.reason, test.metadata)
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) {
+			t.Errorf("err = %v, want a *ValidationError for %v", err, test.field)
+			continue
+		}
+		if validationErr.Field != test.field {
+			t.Errorf("Field is %v, expected %v", validationErr.Field, test.field)
+		}
+	}
+}
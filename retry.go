@@ -0,0 +1,103 @@
+package stripe
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls how the *Stripe retries requests that fail with a network
+// error, a timeout, a 429, or a 500, 502, 503, or 504 response.
+//
+// Only requests that are safe to repeat are retried: GET requests, and requests
+// carrying an Idempotency-Key header.
+type RetryPolicy struct {
+	// MaxAttempts is the most times a request will be sent, including the first
+	// attempt. Values below 2 disable retries.
+	MaxAttempts int
+	// MinBackoff is the delay before the first retry. It doubles for every
+	// subsequent retry.
+	MinBackoff time.Duration
+	// MaxBackoff caps the delay between retries, if greater than zero. It caps
+	// a delay asked for by the server's Retry-After header too.
+	MaxBackoff time.Duration
+	// Jitter is the fraction, between 0 and 1, of each delay that is randomised
+	// to keep clients from retrying in lockstep.
+	Jitter float64
+}
+
+// DefaultRetryPolicy is a reasonable RetryPolicy for most uses.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	MinBackoff:  500 * time.Millisecond,
+	MaxBackoff:  8 * time.Second,
+	Jitter:      0.5,
+}
+
+// backoff returns how long to wait after the attempt numbered attempt failed.
+// A positive retryAfter, as sent by the server, takes precedence, but is still
+// capped by MaxBackoff.
+func (policy RetryPolicy) backoff(attempt int, retryAfter time.Duration) time.Duration {
+	if retryAfter > 0 {
+		if policy.MaxBackoff > 0 && retryAfter > policy.MaxBackoff {
+			return policy.MaxBackoff
+		}
+		return retryAfter
+	}
+	delay := float64(policy.MinBackoff) * math.Pow(2, float64(attempt-1))
+	if policy.MaxBackoff > 0 && delay > float64(policy.MaxBackoff) {
+		delay = float64(policy.MaxBackoff)
+	}
+	if policy.Jitter > 0 {
+		jitter := math.Min(policy.Jitter, 1)
+		delay -= delay * jitter * rand.Float64()
+	}
+	return time.Duration(delay)
+}
+
+// retryable reports whether a request that failed with status may be sent again.
+func retryable(method string, header http.Header, status int) bool {
+	if method != "GET" && header.Get("Idempotency-Key") == "" {
+		return false
+	}
+	switch status {
+	case 0, 429, 500, 502, 503, 504:
+		return true
+	}
+	return false
+}
+
+// parseRetryAfter decodes a Retry-After header given in either seconds or as
+// an HTTP date. It returns 0 if the header is empty or malformed.
+func parseRetryAfter(header string) time.Duration {
+	if header == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(header); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(header); err == nil {
+		if delay := time.Until(date); delay > 0 {
+			return delay
+		}
+	}
+	return 0
+}
+
+// RetryError is returned when a request still failed after being retried. Err
+// is the error from the final attempt.
+type RetryError struct {
+	Attempts int
+	Err      error
+}
+
+func (err *RetryError) Error() string {
+	return fmt.Sprintf("%v (after %d attempts)", err.Err, err.Attempts)
+}
+
+func (err *RetryError) Unwrap() error {
+	return err.Err
+}
@@ -0,0 +1,98 @@
+package stripe
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+var testRetryPolicy = RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond, Jitter: 0.5}
+
+func TestRetrySucceeds(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests < 3 {
+			w.WriteHeader(503)
+			return
+		}
+		w.Write([]byte(`{"id": "ch_123", "object": "charge"}`))
+	}))
+	defer server.Close()
+
+	API := New("sk_test_key", WithBaseURL(server.URL+"/v1/"), WithRetryPolicy(testRetryPolicy))
+	charge, err := API.GetCharge("ch_123")
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	if charge.ID != "ch_123" {
+		t.Errorf("charge.ID is %v, expected %v", charge.ID, "ch_123")
+	}
+	if requests != 3 {
+		t.Errorf("requests is %v, expected %v", requests, 3)
+	}
+}
+
+func TestRetryExhausted(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Retry-After", "0")
+		w.WriteHeader(500)
+		w.Write([]byte(`{"error": {"type": "api_error", "message": "Something went wrong."}}`))
+	}))
+	defer server.Close()
+
+	API := New("sk_test_key", WithBaseURL(server.URL+"/v1/"), WithRetryPolicy(testRetryPolicy))
+	_, err := API.ListCustomers(-1, -1)
+	var retryErr *RetryError
+	if !errors.As(err, &retryErr) {
+		t.Fatalf("err = %#v, want a *RetryError", err)
+	}
+	if retryErr.Attempts != 3 || requests != 3 {
+		t.Errorf("Attempts, requests are %v, %v, expected %v", retryErr.Attempts, requests, 3)
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Errorf("err = %#v, want it to wrap an *APIError", err)
+	}
+}
+
+func TestRetrySkipsUnsafeRequests(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(503)
+	}))
+	defer server.Close()
+
+	API := New("sk_test_key", WithBaseURL(server.URL+"/v1/"), WithRetryPolicy(testRetryPolicy))
+	_, err := API.CreateCharge(&Token{ID: "tok_123"}, 500, "usd", "")
+	if _, ok := err.(*ServerError); !ok {
+		t.Errorf("err = %#v, want a *ServerError", err)
+	}
+	if requests != 1 {
+		t.Errorf("requests is %v, expected %v", requests, 1)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	policy := RetryPolicy{MinBackoff: 100 * time.Millisecond, MaxBackoff: 300 * time.Millisecond}
+	for attempt, want := range []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 300 * time.Millisecond} {
+		if got := policy.backoff(attempt+1, 0); got != want {
+			t.Errorf("backoff(%v) is %v, expected %v", attempt+1, got, want)
+		}
+	}
+	if got := policy.backoff(1, 250*time.Millisecond); got != 250*time.Millisecond {
+		t.Errorf("backoff with Retry-After is %v, expected %v", got, 250*time.Millisecond)
+	}
+	if got := policy.backoff(1, 2*time.Second); got != 300*time.Millisecond {
+		t.Errorf("backoff with a long Retry-After is %v, expected %v", got, 300*time.Millisecond)
+	}
+	policy.MaxBackoff = 0
+	if got := policy.backoff(1, 2*time.Second); got != 2*time.Second {
+		t.Errorf("backoff with Retry-After and no MaxBackoff is %v, expected %v", got, 2*time.Second)
+	}
+}
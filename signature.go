@@ -0,0 +1,146 @@
+package stripe
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultSignatureTolerance is how old a webhook's signature may be before a
+// SignatureVerifier rejects it, unless told otherwise.
+const DefaultSignatureTolerance = 5 * time.Minute
+
+var (
+	// ErrNoSignature is returned when a webhook has no usable Stripe-Signature header.
+	ErrNoSignature = errors.New("stripe: webhook has no signature")
+	// ErrBadSignature is returned when none of a webhook's signatures match any secret.
+	ErrBadSignature = errors.New("stripe: webhook signature does not match")
+	// ErrStaleSignature is returned when a webhook's signature is outside the allowed tolerance.
+	ErrStaleSignature = errors.New("stripe: webhook signature timestamp is outside the tolerance")
+)
+
+// EventVerifier authenticates a webhook request and returns the Event it
+// carries. payload is the request's body, which has already been read.
+type EventVerifier interface {
+	VerifyEvent(r *http.Request, payload []byte) (*Event, error)
+}
+
+// SignatureVerifier authenticates webhooks by checking their Stripe-Signature
+// header, an HMAC-SHA256 of the payload keyed with the endpoint's signing secret.
+type SignatureVerifier struct {
+	// Secrets holds every signing secret a webhook may be signed with. Listing
+	// both the old and new secret allows them to be rotated without downtime.
+	Secrets []string
+	// Tolerance is how far a signature's timestamp may be from the current time,
+	// which protects against replayed webhooks. DefaultSignatureTolerance is used
+	// if it is zero; a negative Tolerance disables the check.
+	Tolerance time.Duration
+	// Now returns the current time. time.Now is used if it is nil.
+	Now func() time.Time
+}
+
+// NewSignatureVerifier returns a *SignatureVerifier that accepts webhooks
+// signed with any of secrets.
+func NewSignatureVerifier(secrets ...string) *SignatureVerifier {
+	return &SignatureVerifier{Secrets: secrets}
+}
+
+// VerifyEvent satisfies EventVerifier.
+func (v *SignatureVerifier) VerifyEvent(r *http.Request, payload []byte) (*Event, error) {
+	return v.Verify(payload, r.Header.Get("Stripe-Signature"))
+}
+
+// Verify checks that header is a valid signature of payload, and if so decodes
+// payload into an *Event.
+func (v *SignatureVerifier) Verify(payload []byte, header string) (*Event, error) {
+	timestamp, signatures, err := parseSignatureHeader(header)
+	if err != nil {
+		return nil, err
+	}
+	tolerance := v.Tolerance
+	if tolerance == 0 {
+		tolerance = DefaultSignatureTolerance
+	}
+	if tolerance > 0 {
+		now := time.Now
+		if v.Now != nil {
+			now = v.Now
+		}
+		age := now().Sub(timestamp)
+		if age > tolerance || age < -tolerance {
+			return nil, ErrStaleSignature
+		}
+	}
+	if !v.matches(payload, timestamp, signatures) {
+		return nil, ErrBadSignature
+	}
+	var event *Event
+	err = json.Unmarshal(payload, &event)
+	if err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+func (v *SignatureVerifier) matches(payload []byte, timestamp time.Time, signatures [][]byte) bool {
+	for _, secret := range v.Secrets {
+		expected := computeSignature(payload, secret, timestamp)
+		for _, signature := range signatures {
+			if hmac.Equal(expected, signature) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// parseSignatureHeader splits a Stripe-Signature header, e.g.
+// "t=1492774577,v1=5257a869...", into its timestamp and v1 signatures.
+func parseSignatureHeader(header string) (timestamp time.Time, signatures [][]byte, err error) {
+	var unix int64
+	for _, pair := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			unix, err = strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return time.Time{}, nil, ErrNoSignature
+			}
+		case "v1":
+			signature, err := hex.DecodeString(value)
+			if err != nil {
+				continue
+			}
+			signatures = append(signatures, signature)
+		}
+	}
+	if unix == 0 || len(signatures) == 0 {
+		return time.Time{}, nil, ErrNoSignature
+	}
+	return time.Unix(unix, 0), signatures, nil
+}
+
+func computeSignature(payload []byte, secret string, timestamp time.Time) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp.Unix(), 10)))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// SignPayload returns a Stripe-Signature header value signing payload with
+// secret at timestamp, the way Stripe signs webhooks. It is meant for
+// constructing webhooks in tests.
+func SignPayload(payload []byte, secret string, timestamp time.Time) string {
+	signature := hex.EncodeToString(computeSignature(payload, secret, timestamp))
+	return "t=" + strconv.FormatInt(timestamp.Unix(), 10) + ",v1=" + signature
+}
@@ -0,0 +1,63 @@
+package stripe
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignatureVerifier(t *testing.T) {
+	now := time.Unix(1360000000, 0)
+	payload := []byte(chargeSucceeded)
+	verifier := &SignatureVerifier{
+		Secrets: []string{"whsec_old", "whsec_new"},
+		Now:     func() time.Time { return now },
+	}
+
+	tests := []struct {
+		name   string
+		header string
+		want   error
+	}{
+		{"current secret", SignPayload(payload, "whsec_new", now), nil},
+		{"rotated secret", SignPayload(payload, "whsec_old", now.Add(-time.Minute)), nil},
+		{"wrong secret", SignPayload(payload, "whsec_other", now), ErrBadSignature},
+		{"stale", SignPayload(payload, "whsec_new", now.Add(-10*time.Minute)), ErrStaleSignature},
+		{"future", SignPayload(payload, "whsec_new", now.Add(10*time.Minute)), ErrStaleSignature},
+		{"missing", "", ErrNoSignature},
+		{"no v1", "t=1360000000,v0=abcdef", ErrNoSignature},
+	}
+	for _, test := range tests {
+		event, err := verifier.Verify(payload, test.header)
+		if err != test.want {
+			t.Errorf("%v: err = %v, want %v", test.name, err, test.want)
+			continue
+		}
+		if err == nil && event.ID != "evt_123" {
+			t.Errorf("%v: event.ID is %v, expected %v", test.name, event.ID, "evt_123")
+		}
+	}
+
+	tampered := []byte(strings.Replace(chargeSucceeded, "500", "5", 1))
+	if _, err := verifier.Verify(tampered, SignPayload(payload, "whsec_new", now)); err != ErrBadSignature {
+		t.Errorf("tampered: err = %v, want %v", err, ErrBadSignature)
+	}
+}
+
+func TestWebhookRejectsUnsignedEvents(t *testing.T) {
+	handler := NewWebhookHandler(NewDispatcher())
+	handler.Verifier = NewSignatureVerifier("whsec_test")
+
+	if recorder := postEvent(handler, chargeSucceeded); recorder.Code != 400 {
+		t.Errorf("unsigned: status is %v, expected %v", recorder.Code, 400)
+	}
+
+	recorder := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/webhook", strings.NewReader(chargeSucceeded))
+	req.Header.Set("Stripe-Signature", SignPayload([]byte(chargeSucceeded), "whsec_test", time.Now()))
+	handler.ServeHTTP(recorder, req)
+	if recorder.Code != 200 {
+		t.Errorf("signed: status is %v, expected %v", recorder.Code, 200)
+	}
+}
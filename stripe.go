@@ -3,12 +3,15 @@ package stripe
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 )
 
 var (
@@ -20,20 +23,114 @@ type Stripe struct {
 	Host    string
 	Version int
 	AuthKey string
+
+	// BaseURL overrides Host and Version when set. It must include the scheme and
+	// the version path, e.g. "http://localhost:12111/v1/".
+	BaseURL string
+	// Client is used to perform every request. http.DefaultClient is used if nil.
+	Client *http.Client
+	// UserAgent is sent as the User-Agent header of every request, if set.
+	UserAgent string
+	// Timeout bounds the duration of each attempt at a request, if greater than zero.
+	Timeout time.Duration
+	// Retry controls whether and how failed requests are retried. The zero value
+	// disables retries.
+	Retry RetryPolicy
+	// Middleware wraps every round trip to the Stripe API, outermost first.
+	Middleware []Middleware
+	// AutoIdempotencyKeys makes every POST request carry a generated
+	// Idempotency-Key header unless the request's context supplies one.
+	AutoIdempotencyKeys bool
+}
+
+// Option configures a *Stripe when passed to New.
+type Option func(*Stripe)
+
+// WithHTTPClient makes the *Stripe send its requests through client, which allows
+// timeouts, proxies, and TLS settings to be configured.
+func WithHTTPClient(client *http.Client) Option {
+	return func(stripe *Stripe) {
+		stripe.Client = client
+	}
+}
+
+// WithBaseURL points the *Stripe at baseURL instead of https://api.stripe.com/v1/.
+// baseURL must include the scheme, and the version path if one is required.
+func WithBaseURL(baseURL string) Option {
+	return func(stripe *Stripe) {
+		stripe.BaseURL = baseURL
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with every request.
+func WithUserAgent(userAgent string) Option {
+	return func(stripe *Stripe) {
+		stripe.UserAgent = userAgent
+	}
+}
+
+// WithTimeout bounds the duration of every request made by the *Stripe.
+func WithTimeout(timeout time.Duration) Option {
+	return func(stripe *Stripe) {
+		stripe.Timeout = timeout
+	}
+}
+
+// WithRetryPolicy makes the *Stripe retry failed requests according to policy.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(stripe *Stripe) {
+		stripe.Retry = policy
+	}
 }
 
-func New(auth string) *Stripe {
-	return &Stripe{HOST, VERSION, strings.TrimSpace(auth)}
+// WithMiddleware appends middleware to the chain wrapping every round trip
+// to the Stripe API. The first Middleware passed is the outermost.
+func WithMiddleware(middleware ...Middleware) Option {
+	return func(stripe *Stripe) {
+		stripe.Middleware = append(stripe.Middleware, middleware...)
+	}
+}
+
+// New returns a *Stripe that authenticates with auth, configured by opts.
+func New(auth string, opts ...Option) *Stripe {
+	stripe := &Stripe{
+		Host:    HOST,
+		Version: VERSION,
+		AuthKey: strings.TrimSpace(auth),
+	}
+	for _, opt := range opts {
+		opt(stripe)
+	}
+	return stripe
+}
+
+func (stripe *Stripe) baseURL() string {
+	if stripe.BaseURL == "" {
+		return "https://" + stripe.Host + "/v" + strconv.Itoa(stripe.Version) + "/"
+	}
+	if !strings.HasSuffix(stripe.BaseURL, "/") {
+		return stripe.BaseURL + "/"
+	}
+	return stripe.BaseURL
+}
+
+func (stripe *Stripe) client() *http.Client {
+	if stripe.Client == nil {
+		return http.DefaultClient
+	}
+	return stripe.Client
 }
 
 type BadRequestError struct {
-	Message string        "message"
-	Request *http.Request "request"
+	Message string        `json:"message"`
+	Request *http.Request `json:"-"`
 }
 
+// Error describes the failed request by its method and URL only, so that API
+// keys and card details in its headers and body are never exposed.
 func (err *BadRequestError) Error() string {
 	if err.Request != nil {
-		err.Message = fmt.Sprintf("%v\nRequest: %v", err.Message, err.Request)
+		return fmt.Sprintf("%v\nRequest: %v %v", err.Message, err.Request.Method, err.Request.URL)
 	}
 	return err.Message
 }
@@ -45,9 +142,11 @@ func ThrowBadRequest(req *http.Request) *BadRequestError {
 	}
 }
 
+// UnauthorizedError is returned when Stripe rejects the API key. Auth holds the
+// key with all but its last four characters redacted.
 type UnauthorizedError struct {
-	Message string "message"
-	Auth    string "auth"
+	Message string `json:"message"`
+	Auth    string `json:"auth"`
 }
 
 func (err *UnauthorizedError) Error() string {
@@ -60,18 +159,20 @@ func (err *UnauthorizedError) Error() string {
 func ThrowUnauthorized(req *http.Request) *UnauthorizedError {
 	return &UnauthorizedError{
 		Message: "Error: Unauthorized.",
-		Auth:    req.Header["Authorization"][0],
+		Auth:    redactAuthorization(req.Header.Get("Authorization")),
 	}
 }
 
 type RequestFailedError struct {
-	Message string        "message"
-	Request *http.Request "request"
+	Message string        `json:"message"`
+	Request *http.Request `json:"-"`
 }
 
+// Error describes the failed request by its method and URL only, so that API
+// keys and card details in its headers and body are never exposed.
 func (err *RequestFailedError) Error() string {
 	if err.Request != nil {
-		err.Message = fmt.Sprintf("%v\nRequest: %v", err.Message, err.Request)
+		return fmt.Sprintf("%v\nRequest: %v %v", err.Message, err.Request.Method, err.Request.URL)
 	}
 	return err.Message
 }
@@ -84,13 +185,13 @@ func ThrowRequestFailed(req *http.Request) *RequestFailedError {
 }
 
 type NotFoundError struct {
-	Message string   "message"
-	URL     *url.URL "url"
+	Message string   `json:"message"`
+	URL     *url.URL `json:"url"`
 }
 
 func (err *NotFoundError) Error() string {
 	if err.URL != nil {
-		err.Message = fmt.Sprintf("%v\nURL: %v", err.Message, err.URL)
+		return fmt.Sprintf("%v\nURL: %v", err.Message, err.URL)
 	}
 	return err.Message
 }
@@ -103,7 +204,7 @@ func ThrowNotFound(req *http.Request) *NotFoundError {
 }
 
 type ServerError struct {
-	Message string "message"
+	Message string `json:"message"`
 }
 
 func (err *ServerError) Error() string {
@@ -117,7 +218,7 @@ func ThrowServer(req *http.Request) *ServerError {
 }
 
 type UnknownError struct {
-	Message string "message"
+	Message string `json:"message"`
 }
 
 func (err *UnknownError) Error() string {
@@ -130,33 +231,122 @@ func ThrowUnknown(code int) *UnknownError {
 	}
 }
 
+// ValidationError is returned when a request is found to be malformed before it
+// is sent to Stripe. Field holds the name of the offending parameter.
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (err *ValidationError) Error() string {
+	return fmt.Sprintf("Invalid %v: %v", err.Field, err.Message)
+}
+
+func invalid(field, message string) *ValidationError {
+	return &ValidationError{Field: field, Message: message}
+}
+
+// validCurrency reports whether currency looks like a three-letter ISO currency code.
+func validCurrency(currency string) bool {
+	if len(currency) != 3 {
+		return false
+	}
+	for _, r := range currency {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+// RawError is the error object Stripe returns in the body of a failed request.
 type RawError struct {
-	Type    string "type"
-	Param   string "param"
-	Code    string "code"
-	Message string "message"
+	Type        string `json:"type"`
+	Param       string `json:"param"`
+	Code        string `json:"code"`
+	Message     string `json:"message"`
+	DeclineCode string `json:"decline_code"`
+	Charge      string `json:"charge"` // The ID of the failed Charge, for card errors
 }
 
 func (err *RawError) Error() string {
 	return fmt.Sprintf("Error (%v): %v\nType: %v\nParam: %v", err.Code, err.Message, err.Type, err.Param)
 }
 
+// typed converts *err into the error type matching its Type: *InvalidRequestError,
+// *APIError, or *CardError. Errors of an unrecognised type are returned as-is.
+func (err *RawError) typed(status int) error {
+	switch err.Type {
+	case "invalid_request_error":
+		return &InvalidRequestError{
+			Code:       err.Code,
+			Param:      err.Param,
+			Message:    err.Message,
+			StatusCode: status,
+		}
+	case "api_error":
+		return &APIError{
+			Code:       err.Code,
+			Param:      err.Param,
+			Message:    err.Message,
+			StatusCode: status,
+		}
+	case "card_error":
+		return &CardError{
+			Code:        err.Code,
+			Param:       err.Param,
+			Message:     err.Message,
+			DeclineCode: err.DeclineCode,
+			ChargeID:    err.Charge,
+			StatusCode:  status,
+		}
+	}
+	return err
+}
+
+// InvalidRequestError is returned when a request has invalid parameters.
 type InvalidRequestError struct {
-	Code    string
-	Param   string
-	Message string
+	Code       string
+	Param      string
+	Message    string
+	StatusCode int
 }
 
+func (err *InvalidRequestError) Error() string {
+	if err.Param != "" {
+		return fmt.Sprintf("Invalid request (%v): %v", err.Param, err.Message)
+	}
+	return fmt.Sprintf("Invalid request: %v", err.Message)
+}
+
+// APIError is returned when Stripe has a problem of its own handling a request.
 type APIError struct {
-	Code    string
-	Param   string
-	Message string
+	Code       string
+	Param      string
+	Message    string
+	StatusCode int
+}
+
+func (err *APIError) Error() string {
+	return fmt.Sprintf("API error: %v", err.Message)
 }
 
+// CardError is returned when a card can't be charged. Details describes Code in
+// plain English; DeclineCode holds the issuer's reason when the card was declined.
 type CardError struct {
-	Code    string
-	Param   string
-	Message string
+	Code        string
+	Param       string
+	Message     string
+	DeclineCode string
+	ChargeID    string
+	StatusCode  int
+}
+
+func (err *CardError) Error() string {
+	if err.DeclineCode != "" {
+		return fmt.Sprintf("Card error (%v, %v): %v", err.Code, err.DeclineCode, err.Message)
+	}
+	return fmt.Sprintf("Card error (%v): %v", err.Code, err.Message)
 }
 
 func (c *CardError) Details() string {
@@ -189,41 +379,147 @@ func (c *CardError) Details() string {
 	return ""
 }
 
-func (stripe *Stripe) request(method, url string, body string) (resp []byte, err error) {
-	baseURL := "https://" + stripe.Host + "/v" + strconv.Itoa(stripe.Version) + "/"
-	rbody := bytes.NewBufferString(body)
-	req, err := http.NewRequest(method, baseURL+url, rbody)
+// request performs a call to the Stripe API, retrying it according to
+// stripe.Retry. If ctx is canceled or its deadline passes before the call
+// completes, ctx.Err() is returned unwrapped, so callers can compare it against
+// context.Canceled and context.DeadlineExceeded.
+func (stripe *Stripe) request(ctx context.Context, method, url string, body string) (resp []byte, err error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	header := make(http.Header)
+	if method == "POST" {
+		key := idempotencyKey(ctx)
+		if key == "" && stripe.AutoIdempotencyKeys {
+			if key, err = newIdempotencyKey(); err != nil {
+				return nil, err
+			}
+		}
+		if key != "" {
+			header.Set("Idempotency-Key", key)
+		}
+	}
+	roundTrip := stripe.roundTrip()
+	for attempts := 1; ; attempts++ {
+		if err = ctx.Err(); err != nil {
+			return nil, err
+		}
+		call := &Call{
+			Method:  method,
+			Path:    url,
+			Body:    body,
+			Header:  header.Clone(),
+			Attempt: attempts,
+		}
+		resp, err = roundTrip(ctx, call)
+		if err == nil {
+			return resp, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if attempts >= stripe.Retry.MaxAttempts || !retryable(method, header, call.StatusCode) {
+			if attempts > 1 {
+				err = &RetryError{Attempts: attempts, Err: err}
+			}
+			return nil, err
+		}
+		timer := time.NewTimer(stripe.Retry.backoff(attempts, call.retryAfter))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// attempt makes the single round trip to the Stripe API described by *call,
+// filling in its StatusCode, RequestID, and Latency, and returning the response
+// body. It is the innermost RoundTrip wrapped by the *Stripe's Middleware.
+func (stripe *Stripe) attempt(ctx context.Context, call *Call) (resp []byte, err error) {
+	if stripe.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, stripe.Timeout)
+		defer cancel()
+	}
+	rbody := bytes.NewBufferString(call.Body)
+	req, err := http.NewRequestWithContext(ctx, call.Method, stripe.baseURL()+call.Path, rbody)
 	if err != nil {
 		return nil, err
 	}
+	for key, values := range call.Header {
+		req.Header[key] = values
+	}
 	req.SetBasicAuth(stripe.AuthKey, "")
-	hresp, err := http.DefaultClient.Do(req)
+	if call.Body != "" {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	if stripe.UserAgent != "" {
+		req.Header.Set("User-Agent", stripe.UserAgent)
+	}
+	start := time.Now()
+	defer func() {
+		call.Latency = time.Since(start)
+	}()
+	hresp, err := stripe.client().Do(req)
 	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
 		return nil, err
 	}
-	switch hresp.StatusCode {
-	case 400:
-		return nil, ThrowBadRequest(req)
+	defer hresp.Body.Close()
+	call.StatusCode = hresp.StatusCode
+	call.RequestID = hresp.Header.Get("Request-Id")
+	resp, err = ioutil.ReadAll(hresp.Body)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		return nil, err
+	}
+	if hresp.StatusCode == 200 {
+		return resp, nil
+	}
+	call.retryAfter = parseRetryAfter(hresp.Header.Get("Retry-After"))
+	return nil, responseError(req, hresp.StatusCode, resp)
+}
+
+// responseError builds the error for a non-200 response. The error envelope in
+// body is decoded into a typed error where possible; when it can't be, an error
+// is chosen based on the status code alone.
+func responseError(req *http.Request, status int, body []byte) error {
+	var envelope struct {
+		Error *RawError `json:"error"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		envelope.Error = nil
+	}
+	switch status {
 	case 401:
-		return nil, ThrowUnauthorized(req)
-	case 402:
-		return nil, ThrowRequestFailed(req)
+		err := ThrowUnauthorized(req)
+		if envelope.Error != nil && envelope.Error.Message != "" {
+			err.Message = envelope.Error.Message
+		}
+		return err
 	case 404:
-		return nil, ThrowNotFound(req)
-	case 500:
-		return nil, ThrowServer(req)
-	case 502:
-		return nil, ThrowServer(req)
-	case 503:
-		return nil, ThrowServer(req)
-	case 504:
-		return nil, ThrowServer(req)
-	case 200:
-		resp, err = ioutil.ReadAll(hresp.Body)
-		hresp.Body.Close()
-		return resp, err
-	default:
-		return nil, ThrowUnknown(hresp.StatusCode)
-	}
-	return nil, ThrowUnknown(hresp.StatusCode)
+		err := ThrowNotFound(req)
+		if envelope.Error != nil && envelope.Error.Message != "" {
+			err.Message = envelope.Error.Message
+		}
+		return err
+	}
+	if envelope.Error != nil && envelope.Error.Type != "" {
+		return envelope.Error.typed(status)
+	}
+	switch status {
+	case 400:
+		return ThrowBadRequest(req)
+	case 402:
+		return ThrowRequestFailed(req)
+	case 500, 502, 503, 504:
+		return ThrowServer(req)
+	}
+	return ThrowUnknown(status)
 }
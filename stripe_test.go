@@ -0,0 +1,182 @@
+package stripe
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestOptions(t *testing.T) {
+	var gotPath, gotAgent, gotKey string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotAgent = r.Header.Get("User-Agent")
+		gotKey, _, _ = r.BasicAuth()
+		w.Write([]byte(`{"id": "cus_123", "object": "customer"}`))
+	}))
+	defer server.Close()
+
+	API := New(" sk_test_key\n", WithBaseURL(server.URL+"/v1"), WithUserAgent("stripe-test/1.0"), WithTimeout(time.Second), WithHTTPClient(server.Client()))
+	customer, err := API.GetCustomer("cus_123")
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	if customer.ID != "cus_123" {
+		t.Errorf("customer.ID is %v, expected %v", customer.ID, "cus_123")
+	}
+	if gotPath != "/v1/customers/cus_123" {
+		t.Errorf("path is %v, expected %v", gotPath, "/v1/customers/cus_123")
+	}
+	if gotAgent != "stripe-test/1.0" {
+		t.Errorf("User-Agent is %v, expected %v", gotAgent, "stripe-test/1.0")
+	}
+	if gotKey != "sk_test_key" {
+		t.Errorf("auth key is %v, expected %v", gotKey, "sk_test_key")
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer server.Close()
+
+	API := New("sk_test_key", WithBaseURL(server.URL+"/v1/"), WithTimeout(10*time.Millisecond))
+	_, err := API.GetCustomer("cus_123")
+	if err != context.DeadlineExceeded {
+		t.Fatalf("err = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cancel()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	API := New("sk_test_key", WithBaseURL(server.URL+"/v1/"))
+	_, err := API.GetChargeCtx(ctx, "ch_123")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestContextDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	API := New("sk_test_key", WithBaseURL(server.URL+"/v1/"))
+	_, err := API.ListEventsCtx(ctx, nil, -1, -1, "")
+	if err != context.DeadlineExceeded {
+		t.Fatalf("err = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestCardError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(402)
+		w.Write([]byte(`{"error": {"type": "card_error", "code": "card_declined", "decline_code": "insufficient_funds", "param": "", "message": "Your card was declined.", "charge": "ch_123"}}`))
+	}))
+	defer server.Close()
+
+	API := New("sk_test_key", WithBaseURL(server.URL+"/v1/"))
+	_, err := API.CreateCharge(&Token{ID: "tok_123"}, 500, "usd", "")
+	var cardErr *CardError
+	if !errors.As(err, &cardErr) {
+		t.Fatalf("err = %#v, want a *CardError", err)
+	}
+	if cardErr.Code != "card_declined" {
+		t.Errorf("Code is %v, expected %v", cardErr.Code, "card_declined")
+	}
+	if cardErr.DeclineCode != "insufficient_funds" {
+		t.Errorf("DeclineCode is %v, expected %v", cardErr.DeclineCode, "insufficient_funds")
+	}
+	if cardErr.ChargeID != "ch_123" {
+		t.Errorf("ChargeID is %v, expected %v", cardErr.ChargeID, "ch_123")
+	}
+	if cardErr.Details() != "The card was declined." {
+		t.Errorf("Details() is %v, expected %v", cardErr.Details(), "The card was declined.")
+	}
+}
+
+func TestInvalidRequestError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(400)
+		w.Write([]byte(`{"error": {"type": "invalid_request_error", "param": "amount", "message": "Amount must be at least 50 cents"}}`))
+	}))
+	defer server.Close()
+
+	API := New("sk_test_key", WithBaseURL(server.URL+"/v1/"))
+	_, err := API.CreateCharge(&Token{ID: "tok_123"}, 5, "usd", "")
+	var reqErr *InvalidRequestError
+	if !errors.As(err, &reqErr) {
+		t.Fatalf("err = %#v, want an *InvalidRequestError", err)
+	}
+	if reqErr.Param != "amount" || reqErr.StatusCode != 400 {
+		t.Errorf("Param, StatusCode are %v, %v, expected %v, %v", reqErr.Param, reqErr.StatusCode, "amount", 400)
+	}
+}
+
+func TestEmbeddedError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"error": {"type": "api_error", "message": "Something went wrong."}}`))
+	}))
+	defer server.Close()
+
+	API := New("sk_test_key", WithBaseURL(server.URL+"/v1/"))
+	plan, err := API.GetPlan("gold")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("err = %#v, want an *APIError", err)
+	}
+	if plan != nil {
+		t.Errorf("plan is %v, expected nil", plan)
+	}
+}
+
+func TestValidation(t *testing.T) {
+	var nilCard *Card
+	tests := []struct {
+		field  string
+		values func(*url.Values) error
+	}{
+		{"card", nilCard.ChargeValues},
+		{"card[number]", (&Card{ExpMonth: 3, ExpYear: 2030}).ChargeValues},
+		{"card[exp_month]", (&Card{Number: "4242424242424242", ExpMonth: 13, ExpYear: 2030}).ChargeValues},
+		{"card", (&Token{}).ChargeValues},
+		{"customer", (&Customer{}).ChargeValues},
+		{"currency", (&Plan{ID: "gold", Name: "Gold", Amount: 2000, Currency: "dollars", Interval: "month"}).Values},
+		{"interval", (&Plan{ID: "gold", Name: "Gold", Amount: 2000, Currency: "usd", Interval: "fortnight"}).Values},
+		{"percent_off", (&Coupon{PercentOff: 101, Duration: "once"}).Values},
+		{"duration_in_months", (&Coupon{PercentOff: 10, Duration: "repeating"}).Values},
+		{"duration_in_months", (&Coupon{PercentOff: 10, Duration: "once", DurationInMonths: 3}).Values},
+		{"max_redemptions", (&Coupon{PercentOff: 10, Duration: "forever", MaxRedemptions: -1}).Values},
+		{"plan", (&Subscription{CustomerID: "cus_123"}).Values},
+		{"amount", (&InvoiceItem{CustomerID: "cus_123", Currency: "usd"}).Values},
+	}
+	for _, test := range tests {
+		values := make(url.Values)
+		err := test.values(&values)
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) {
+			t.Errorf("err = %#v, want a *ValidationError for %v", err, test.field)
+			continue
+		}
+		if validationErr.Field != test.field {
+			t.Errorf("Field is %v, expected %v", validationErr.Field, test.field)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package stripetest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// brands maps card number prefixes to the brand of card they belong to.
+var brands = []struct {
+	prefix, brand string
+}{
+	{"4", "Visa"},
+	{"34", "American Express"},
+	{"37", "American Express"},
+	{"51", "MasterCard"},
+	{"52", "MasterCard"},
+	{"53", "MasterCard"},
+	{"54", "MasterCard"},
+	{"55", "MasterCard"},
+	{"6011", "Discover"},
+	{"65", "Discover"},
+	{"35", "JCB"},
+	{"30", "Diners Club"},
+	{"36", "Diners Club"},
+	{"38", "Diners Club"},
+}
+
+func brand(number string) string {
+	for _, b := range brands {
+		if strings.HasPrefix(number, b.prefix) {
+			return b.brand
+		}
+	}
+	return "Unknown"
+}
+
+// luhn reports whether number is made up of digits and passes the Luhn check.
+func luhn(number string) bool {
+	if len(number) < 12 || len(number) > 19 {
+		return false
+	}
+	sum := 0
+	for i := range number {
+		c := number[len(number)-1-i]
+		if c < '0' || c > '9' {
+			return false
+		}
+		d := int(c - '0')
+		if i%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return sum%10 == 0
+}
+
+// newCard creates a card object from the card[...] parameters of r, the way
+// Stripe does when a card's details are sent in place of a token.
+func (s *Server) newCard(r *http.Request) (object, *apiError) {
+	number := strings.ReplaceAll(r.Form.Get("card[number]"), " ", "")
+	if number == "" {
+		return nil, missingParam("card[number]")
+	}
+	if !luhn(number) {
+		return nil, cardError("incorrect_number", "number", "Your card number is incorrect.")
+	}
+	month, err := strconv.Atoi(r.Form.Get("card[exp_month]"))
+	if err != nil || month < 1 || month > 12 {
+		return nil, cardError("invalid_expiry_month", "exp_month", "Your card's expiration month is invalid.")
+	}
+	year, err := strconv.Atoi(r.Form.Get("card[exp_year]"))
+	if err != nil || year < 1 {
+		return nil, cardError("invalid_expiry_year", "exp_year", "Your card's expiration year is invalid.")
+	}
+	if year < 100 {
+		year += 2000
+	}
+	now := s.Now()
+	if year < now.Year() || year == now.Year() && month < int(now.Month()) {
+		return nil, cardError("invalid_expiry_year", "exp_year", "Your card's expiration year is invalid.")
+	}
+	cvc := r.Form.Get("card[cvc]")
+	if cvc != "" {
+		want := 3
+		if brand(number) == "American Express" {
+			want = 4
+		}
+		if _, err := strconv.Atoi(cvc); err != nil || len(cvc) != want {
+			return nil, cardError("invalid_cvc", "cvc", "Your card's security code is invalid.")
+		}
+	}
+	cvcCheck, line1Check, zipCheck := checks(number, cvc != "", r.Form.Get("card[address_line1]") != "", r.Form.Get("card[address_zip]") != "")
+	sum := sha256.Sum256([]byte(number))
+	id := s.newID("card")
+	s.cardNumbers[id] = number
+	return object{
+		"id":                  id,
+		"object":              "card",
+		"last4":               number[len(number)-4:],
+		"type":                brand(number),
+		"exp_month":           month,
+		"exp_year":            year,
+		"fingerprint":         hex.EncodeToString(sum[:8]),
+		"country":             "US",
+		"name":                optional(r, "card[name]"),
+		"address_line1":       optional(r, "card[address_line1]"),
+		"address_line2":       optional(r, "card[address_line2]"),
+		"address_state":       optional(r, "card[address_state]"),
+		"address_zip":         optional(r, "card[address_zip]"),
+		"address_country":     optional(r, "card[address_country]"),
+		"cvc_check":           cvcCheck,
+		"address_line1_check": line1Check,
+		"address_zip_check":   zipCheck,
+	}, nil
+}
+
+// card returns the card a request pays with, given either as a token ID in
+// the card parameter, or as card[...] parameters. It returns a nil object if
+// the request has neither.
+func (s *Server) card(r *http.Request) (object, *apiError) {
+	if id := r.Form.Get("card"); id != "" {
+		token, ok := s.tokens[id]
+		if !ok {
+			return nil, invalidRequest("card", "No such token: "+id)
+		}
+		if token["used"] == true {
+			return nil, invalidRequest("card", "You cannot use a Stripe token more than once: "+id+".")
+		}
+		token["used"] = true
+		return token["card"].(object), nil
+	}
+	if _, ok := r.Form["card[number]"]; ok {
+		return s.newCard(r)
+	}
+	return nil, nil
+}
+
+// customerCard is like card, but for attaching the card to a customer, which
+// Stripe verifies with the card's issuer first.
+func (s *Server) customerCard(r *http.Request) (object, *apiError) {
+	card, apiErr := s.card(r)
+	if apiErr != nil || card == nil {
+		return nil, apiErr
+	}
+	if apiErr := s.declined(card, false); apiErr != nil {
+		return nil, apiErr
+	}
+	return card, nil
+}
+
+func (s *Server) createToken(r *http.Request) (interface{}, *apiError) {
+	card, apiErr := s.newCard(r)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	id := s.newID("tok")
+	token := object{
+		"id":       id,
+		"object":   "token",
+		"livemode": false,
+		"created":  s.now(),
+		"used":     false,
+		"card":     card,
+	}
+	s.tokens[id] = token
+	return token, nil
+}
+
+func (s *Server) getToken(r *http.Request) (interface{}, *apiError) {
+	token, ok := s.tokens[r.PathValue("id")]
+	if !ok {
+		return nil, noSuch("token", r.PathValue("id"))
+	}
+	return token, nil
+}
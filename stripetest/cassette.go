@@ -0,0 +1,299 @@
+package stripetest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Mode determines whether a Cassette records, replays, or passes through the
+// requests made with it.
+type Mode int
+
+const (
+	// Replay answers requests with the Cassette's recorded responses, without
+	// making any real requests. A request with no matching recording fails
+	// with an *UnmatchedRequestError.
+	Replay Mode = iota
+	// Record makes real requests, and records them and their responses, to be
+	// written to the Cassette's file by Save.
+	Record
+	// Passthrough makes real requests without recording or replaying anything.
+	Passthrough
+)
+
+func (mode Mode) String() string {
+	switch mode {
+	case Replay:
+		return "replay"
+	case Record:
+		return "record"
+	case Passthrough:
+		return "passthrough"
+	}
+	return fmt.Sprintf("Mode(%d)", int(mode))
+}
+
+// Interaction is a recorded request and the response to it.
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is a request as it is recorded in a Cassette. URL holds only
+// the path and query, so a recording made against one host can be replayed
+// against any other.
+type RecordedRequest struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Body   string `json:"body"`
+}
+
+// RecordedResponse is a response as it is recorded in a Cassette.
+type RecordedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header"`
+	Body       string      `json:"body"`
+}
+
+// recordedHeaders are the response headers kept in recordings.
+var recordedHeaders = []string{"Content-Type", "Request-Id", "Retry-After"}
+
+// UnmatchedRequestError is returned by a replaying Cassette for a request that
+// none of its unused recordings match.
+type UnmatchedRequestError struct {
+	Path    string // The Cassette's file
+	Request RecordedRequest
+}
+
+func (err *UnmatchedRequestError) Error() string {
+	msg := fmt.Sprintf("stripetest: no recording in %s matches %s %s", err.Path, err.Request.Method, err.Request.URL)
+	if err.Request.Body != "" {
+		msg += " with body " + err.Request.Body
+	}
+	return msg
+}
+
+// Cassette is an http.RoundTripper that records requests to the Stripe API and
+// their responses to a JSON file, and replays them from it, so tests can run
+// deterministically against responses from the real API:
+//
+//	cassette, err := stripetest.LoadCassette("testdata/charges.json", stripetest.Replay)
+//	API := stripe.New(key, stripe.WithHTTPClient(cassette.Client()))
+//
+// API keys and card numbers are scrubbed from everything recorded, and the
+// Authorization header is never recorded. A request matches a recording if
+// its method, path and query, and body are the same once scrubbed; identical
+// requests are matched to their recordings in the order they were recorded.
+type Cassette struct {
+	Path string
+	Mode Mode
+
+	// Transport makes the real requests when recording or passing through.
+	// If nil, http.DefaultTransport is used.
+	Transport http.RoundTripper
+
+	mu           sync.Mutex
+	interactions []Interaction
+	used         []bool
+}
+
+// LoadCassette returns a *Cassette for the file at path. When replaying, the
+// file's recordings are loaded, and it's an error for the file not to exist.
+// When recording, the file is replaced by Save.
+func LoadCassette(path string, mode Mode) (*Cassette, error) {
+	c := &Cassette{Path: path, Mode: mode}
+	if mode != Replay {
+		return c, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file struct {
+		Interactions []Interaction `json:"interactions"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("stripetest: decoding %s: %w", path, err)
+	}
+	c.interactions = file.Interactions
+	c.used = make([]bool, len(file.Interactions))
+	return c, nil
+}
+
+// Client returns an *http.Client that makes its requests through the Cassette,
+// for use with stripe.WithHTTPClient.
+func (c *Cassette) Client() *http.Client {
+	return &http.Client{Transport: c}
+}
+
+// Interactions returns the Cassette's recordings.
+func (c *Cassette) Interactions() []Interaction {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Interaction(nil), c.interactions...)
+}
+
+// Save writes the Cassette's recordings to its file, creating any directories
+// needed. It does nothing unless the Cassette is recording.
+func (c *Cassette) Save() error {
+	if c.Mode != Record {
+		return nil
+	}
+	c.mu.Lock()
+	file := struct {
+		Interactions []Interaction `json:"interactions"`
+	}{c.interactions}
+	data, err := json.MarshalIndent(file, "", "  ")
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.Path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(c.Path, append(data, '\n'), 0644)
+}
+
+// RoundTrip implements http.RoundTripper.
+func (c *Cassette) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	recorded := RecordedRequest{
+		Method: req.Method,
+		URL:    scrubURL(req.URL),
+		Body:   scrubForm(string(body)),
+	}
+	switch c.Mode {
+	case Replay:
+		return c.replay(req, recorded)
+	case Record, Passthrough:
+		resp, err := c.transport().RoundTrip(req)
+		if err != nil || c.Mode == Passthrough {
+			return resp, err
+		}
+		return c.record(resp, recorded)
+	}
+	return nil, fmt.Errorf("stripetest: unknown cassette mode %v", c.Mode)
+}
+
+func (c *Cassette) transport() http.RoundTripper {
+	if c.Transport == nil {
+		return http.DefaultTransport
+	}
+	return c.Transport
+}
+
+func (c *Cassette) replay(req *http.Request, recorded RecordedRequest) (*http.Response, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, interaction := range c.interactions {
+		if c.used[i] || interaction.Request != recorded {
+			continue
+		}
+		c.used[i] = true
+		return interaction.Response.response(req), nil
+	}
+	return nil, &UnmatchedRequestError{Path: c.Path, Request: recorded}
+}
+
+func (c *Cassette) record(resp *http.Response, recorded RecordedRequest) (*http.Response, error) {
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	header := make(http.Header)
+	for _, key := range recordedHeaders {
+		if values := resp.Header.Values(key); len(values) > 0 {
+			header[key] = values
+		}
+	}
+	response := RecordedResponse{
+		StatusCode: resp.StatusCode,
+		Header:     header,
+		Body:       scrubText(string(body)),
+	}
+	c.mu.Lock()
+	c.interactions = append(c.interactions, Interaction{Request: recorded, Response: response})
+	c.used = append(c.used, true)
+	c.mu.Unlock()
+	// The caller gets the scrubbed response too, so recording and replaying
+	// behave the same.
+	return response.response(resp.Request), nil
+}
+
+func (r RecordedResponse) response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", r.StatusCode, http.StatusText(r.StatusCode)),
+		StatusCode:    r.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        r.Header.Clone(),
+		Body:          io.NopCloser(strings.NewReader(r.Body)),
+		ContentLength: int64(len(r.Body)),
+		Request:       req,
+	}
+}
+
+const scrubbed = "••••"
+
+var (
+	apiKeyPattern = regexp.MustCompile(`\b([sprk]k_(?:test|live)_)[0-9A-Za-z]+`)
+	panPattern    = regexp.MustCompile(`\b\d{13,19}\b`)
+)
+
+// scrubText masks API keys, and anything that looks like a card number, in s.
+func scrubText(s string) string {
+	s = apiKeyPattern.ReplaceAllString(s, "${1}"+scrubbed)
+	return panPattern.ReplaceAllStringFunc(s, func(digits string) string {
+		if !luhn(digits) {
+			return digits
+		}
+		return scrubbed + digits[len(digits)-4:]
+	})
+}
+
+// scrubForm is like scrubText, but for a URL-encoded form, in which it also
+// masks security codes.
+func scrubForm(body string) string {
+	values, err := url.ParseQuery(body)
+	if err != nil || len(values) == 0 {
+		return scrubText(body)
+	}
+	for key, vals := range values {
+		for i, val := range vals {
+			if key == "cvc" || strings.HasSuffix(key, "[cvc]") {
+				vals[i] = scrubbed
+				continue
+			}
+			vals[i] = scrubText(val)
+		}
+	}
+	return values.Encode()
+}
+
+func scrubURL(u *url.URL) string {
+	path := u.EscapedPath()
+	if u.RawQuery == "" {
+		return path
+	}
+	return path + "?" + scrubForm(u.RawQuery)
+}
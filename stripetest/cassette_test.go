@@ -0,0 +1,57 @@
+package stripetest
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestScrub(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"sk_test_4eC39HqLyjWDarjtT1zdp7dc", "sk_test_••••"},
+		{`{"secret": "rk_live_abc123"}`, `{"secret": "rk_live_••••"}`},
+		{"4242424242424242", "••••4242"},
+		{"1234567890123", "1234567890123"},
+		{"ch_00000000000012", "ch_00000000000012"},
+	}
+	for _, test := range tests {
+		if got := scrubText(test.in); got != test.want {
+			t.Errorf("scrubText(%q) is %q, expected %q", test.in, got, test.want)
+		}
+	}
+	form := scrubForm("card%5Bnumber%5D=4000000000000002&card%5Bcvc%5D=123&amount=2000")
+	values, err := url.ParseQuery(form)
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	if got := values.Get("card[number]"); got != "••••0002" {
+		t.Errorf("card[number] is %q, expected %q", got, "••••0002")
+	}
+	if got := values.Get("card[cvc]"); got != scrubbed {
+		t.Errorf("card[cvc] is %q, expected %q", got, scrubbed)
+	}
+	if got := values.Get("amount"); got != "2000" {
+		t.Errorf("amount is %q, expected %q", got, "2000")
+	}
+}
+
+func TestCassettePassthrough(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	c := &Cassette{Mode: Passthrough}
+	req, _ := http.NewRequest("GET", s.BaseURL()+"charges", nil)
+	req.SetBasicAuth("sk_test_key", "")
+	resp, err := c.Client().Do(req)
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 200 {
+		t.Errorf("status is %v, expected %v", resp.StatusCode, 200)
+	}
+	if n := len(c.Interactions()); n != 0 {
+		t.Errorf("len(Interactions()) is %v, expected %v", n, 0)
+	}
+}
@@ -0,0 +1,275 @@
+package stripetest
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// minimumAmount is the smallest amount, in cents, that can be charged.
+const minimumAmount = 50
+
+// HoldDuration is how long an uncaptured charge can be captured for. After it,
+// the hold expires, and the charge is refunded in full.
+const HoldDuration = 7 * 24 * time.Hour
+
+// fee returns what Stripe keeps of a charge of amount: 2.9% plus 30 cents.
+func fee(amount int64) int64 {
+	return (amount*29+500)/1000 + 30
+}
+
+func (s *Server) createCharge(r *http.Request) (interface{}, *apiError) {
+	amount, ok, apiErr := intParam(r, "amount")
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	if !ok {
+		return nil, missingParam("amount")
+	}
+	if amount < minimumAmount {
+		return nil, invalidRequest("amount", fmt.Sprintf("Amount must be at least %d cents", minimumAmount))
+	}
+	currency := strings.ToLower(r.Form.Get("currency"))
+	if currency == "" {
+		return nil, missingParam("currency")
+	}
+	if len(currency) != 3 {
+		return nil, invalidRequest("currency", "Invalid currency: "+currency)
+	}
+	card, apiErr := s.card(r)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	var customer interface{}
+	if id := r.Form.Get("customer"); id != "" {
+		cus, ok := s.customers[id]
+		if !ok {
+			return nil, noSuch("customer", id)
+		}
+		if card == nil {
+			card, _ = cus["active_card"].(object)
+			if card == nil {
+				return nil, cardError("missing", "card", "Cannot charge a customer that has no active card")
+			}
+		}
+		customer = id
+	}
+	if card == nil {
+		return nil, missingParam("card")
+	}
+	md, apiErr := metadata(r, nil)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	capture := true
+	if value := r.Form.Get("capture"); value != "" {
+		var err error
+		capture, err = strconv.ParseBool(value)
+		if err != nil {
+			return nil, invalidRequest("capture", "Invalid boolean: "+value)
+		}
+	}
+	details := object{
+		"description":          optional(r, "description"),
+		"statement_descriptor": optional(r, "statement_descriptor"),
+		"receipt_email":        optional(r, "receipt_email"),
+		"metadata":             md,
+	}
+	if descriptor := r.Form.Get("statement_descriptor"); utf8.RuneCountInString(descriptor) > 22 || strings.ContainsAny(descriptor, `<>"'`) {
+		return nil, invalidRequest("statement_descriptor", "The statement descriptor must be at most 22 characters, and must not contain <, >, \", or '.")
+	}
+	ship, apiErr := shipping(r)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	if ship != nil {
+		details["shipping"] = ship
+	}
+	if applicationFee, ok, apiErr := intParam(r, "application_fee"); apiErr != nil {
+		return nil, apiErr
+	} else if ok {
+		if applicationFee < 0 || applicationFee > amount {
+			return nil, invalidRequest("application_fee", "The application fee must be between 0 and the amount of the charge.")
+		}
+		details["application_fee"] = s.newID("fee")
+	}
+	return s.charge(amount, currency, card, customer, details, capture)
+}
+
+// shipping returns the shipping details in r's form, or nil if there are none.
+func shipping(r *http.Request) (object, *apiError) {
+	given := false
+	for key := range r.Form {
+		if strings.HasPrefix(key, "shipping[") {
+			given = true
+			break
+		}
+	}
+	if !given {
+		return nil, nil
+	}
+	for _, key := range []string{"shipping[name]", "shipping[address][line1]"} {
+		if r.Form.Get(key) == "" {
+			return nil, missingParam(key)
+		}
+	}
+	address := object{}
+	for _, key := range []string{"line1", "line2", "city", "state", "postal_code", "country"} {
+		address[key] = optional(r, "shipping[address]["+key+"]")
+	}
+	details := object{"address": address}
+	for _, key := range []string{"name", "phone", "carrier", "tracking_number"} {
+		details[key] = optional(r, "shipping["+key+"]")
+	}
+	return details, nil
+}
+
+// charge creates a charge of amount to card, and records its event. Details
+// holds the rest of the charge's fields, such as its description and metadata.
+// If capture is false, the charge only places a hold on card for amount, to be
+// captured later. If card is declined, the failed charge is kept, and its ID
+// returned in the error.
+func (s *Server) charge(amount int64, currency string, card object, customer interface{}, details object, capture bool) (object, *apiError) {
+	id := s.newID("ch")
+	charge := object{
+		"id":                   id,
+		"object":               "charge",
+		"livemode":             false,
+		"created":              s.now(),
+		"amount":               amount,
+		"currency":             currency,
+		"card":                 card,
+		"customer":             customer,
+		"description":          nil,
+		"statement_descriptor": nil,
+		"receipt_email":        nil,
+		"shipping":             nil,
+		"application_fee":      nil,
+		"fee":                  fee(amount),
+		"paid":                 true,
+		"captured":             capture,
+		"refunded":             false,
+		"amount_refunded":      int64(0),
+		"refunds":              object{"object": "list", "url": "/v1/charges/" + id + "/refunds", "count": 0, "data": []object{}},
+		"failure_code":         nil,
+		"failure_message":      nil,
+		"metadata":             map[string]string{},
+	}
+	for key, value := range details {
+		charge[key] = value
+	}
+	if !capture {
+		// Stripe takes its fee when the charge is captured.
+		charge["fee"] = int64(0)
+	}
+	s.charges[id] = charge
+	if apiErr := s.declined(card, true); apiErr != nil {
+		charge["paid"] = false
+		charge["captured"] = false
+		charge["fee"] = int64(0)
+		charge["failure_code"] = apiErr.Code
+		charge["failure_message"] = apiErr.Message
+		apiErr.Charge = id
+		s.record("charge.failed", charge)
+		return nil, apiErr
+	}
+	s.record("charge.succeeded", charge)
+	return charge, nil
+}
+
+func (s *Server) getCharge(r *http.Request) (interface{}, *apiError) {
+	charge, ok := s.charges[r.PathValue("id")]
+	if !ok {
+		return nil, noSuch("charge", r.PathValue("id"))
+	}
+	return charge, nil
+}
+
+func (s *Server) updateCharge(r *http.Request) (interface{}, *apiError) {
+	charge, ok := s.charges[r.PathValue("id")]
+	if !ok {
+		return nil, noSuch("charge", r.PathValue("id"))
+	}
+	md, apiErr := metadata(r, charge["metadata"].(map[string]string))
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	if _, ok := r.Form["description"]; ok {
+		charge["description"] = optional(r, "description")
+	}
+	charge["metadata"] = md
+	s.record("charge.updated", charge)
+	return charge, nil
+}
+
+func (s *Server) refundCharge(r *http.Request) (interface{}, *apiError) {
+	charge, _, apiErr := s.refundRequest(r)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	return charge, nil
+}
+
+func (s *Server) captureCharge(r *http.Request) (interface{}, *apiError) {
+	charge, ok := s.charges[r.PathValue("id")]
+	if !ok {
+		return nil, noSuch("charge", r.PathValue("id"))
+	}
+	id := charge["id"].(string)
+	switch {
+	case charge["paid"] != true:
+		return nil, invalidRequest("id", "Charge "+id+" has not been paid, so it cannot be captured.")
+	case charge["captured"] == true:
+		return nil, invalidRequest("id", "Charge "+id+" has already been captured.")
+	case charge["refunded"] == true:
+		return nil, invalidRequest("id", "Charge "+id+" has been refunded, so it cannot be captured.")
+	}
+	amount := charge["amount"].(int64)
+	captured, ok, apiErr := intParam(r, "amount")
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	if !ok {
+		captured = amount
+	}
+	if captured < minimumAmount {
+		return nil, invalidRequest("amount", fmt.Sprintf("Amount must be at least %d cents", minimumAmount))
+	}
+	if captured > amount {
+		return nil, invalidRequest("amount", fmt.Sprintf("Capture amount ($%.2f) is greater than the amount authorized ($%.2f)", float64(captured)/100, float64(amount)/100))
+	}
+	// Whatever isn't captured is released, and refunded.
+	charge["captured"] = true
+	charge["fee"] = fee(captured)
+	if captured < amount {
+		s.refund(charge, amount-captured, nil, nil)
+	}
+	s.record("charge.captured", charge)
+	return charge, nil
+}
+
+// expireHolds refunds every uncaptured charge whose hold has expired.
+func (s *Server) expireHolds() {
+	cutoff := s.Now().Add(-HoldDuration).Unix()
+	ids := make([]string, 0, len(s.charges))
+	for id := range s.charges {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		charge := s.charges[id]
+		if charge["paid"] != true || charge["captured"] == true || charge["refunded"] == true || charge["created"].(int64) > cutoff {
+			continue
+		}
+		s.refund(charge, charge["amount"].(int64), nil, nil)
+		s.record("charge.expired", charge)
+	}
+}
+
+func (s *Server) listCharges(r *http.Request) (interface{}, *apiError) {
+	return list(r, s.charges, byCustomer(r))
+}
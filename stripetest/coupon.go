@@ -0,0 +1,125 @@
+package stripetest
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+func (s *Server) createCoupon(r *http.Request) (interface{}, *apiError) {
+	for _, key := range []string{"percent_off", "duration"} {
+		if r.Form.Get(key) == "" {
+			return nil, missingParam(key)
+		}
+	}
+	id := r.Form.Get("id")
+	if id == "" {
+		id = strings.ToUpper(s.newID("coupon"))
+	}
+	if _, ok := s.coupons[id]; ok {
+		return nil, invalidRequest("id", "Coupon already exists.")
+	}
+	percentOff, _, apiErr := intParam(r, "percent_off")
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	if percentOff < 1 || percentOff > 100 {
+		return nil, invalidRequest("percent_off", "Invalid percent_off: must be between 1 and 100")
+	}
+	duration := r.Form.Get("duration")
+	var months interface{}
+	switch duration {
+	case "forever", "once":
+	case "repeating":
+		n, ok, apiErr := intParam(r, "duration_in_months")
+		if apiErr != nil {
+			return nil, apiErr
+		}
+		if !ok {
+			return nil, missingParam("duration_in_months")
+		}
+		if n < 1 {
+			return nil, invalidRequest("duration_in_months", "Invalid duration_in_months: must be at least 1")
+		}
+		months = n
+	default:
+		return nil, invalidRequest("duration", "Invalid duration: must be one of forever, once or repeating")
+	}
+	coupon := object{
+		"id":                 id,
+		"object":             "coupon",
+		"livemode":           false,
+		"created":            s.now(),
+		"percent_off":        percentOff,
+		"duration":           duration,
+		"duration_in_months": months,
+		"max_redemptions":    nil,
+		"redeem_by":          nil,
+		"times_redeemed":     int64(0),
+	}
+	for _, key := range []string{"max_redemptions", "redeem_by"} {
+		n, ok, apiErr := intParam(r, key)
+		if apiErr != nil {
+			return nil, apiErr
+		}
+		if ok {
+			coupon[key] = n
+		}
+	}
+	s.coupons[id] = coupon
+	s.record("coupon.created", coupon)
+	return coupon, nil
+}
+
+// applyCoupon redeems the coupon with an ID of id as a discount for customer.
+func (s *Server) applyCoupon(customer object, id string) *apiError {
+	coupon, ok := s.coupons[id]
+	if !ok {
+		return noSuch("coupon", id)
+	}
+	if max, ok := coupon["max_redemptions"].(int64); ok && coupon["times_redeemed"].(int64) >= max {
+		return invalidRequest("coupon", "Coupon expired: "+id)
+	}
+	if by, ok := coupon["redeem_by"].(int64); ok && s.now() > by {
+		return invalidRequest("coupon", "Coupon expired: "+id)
+	}
+	coupon["times_redeemed"] = coupon["times_redeemed"].(int64) + 1
+	start := s.now()
+	var end interface{}
+	if months, ok := coupon["duration_in_months"].(int64); ok {
+		end = time.Unix(start, 0).AddDate(0, int(months), 0).Unix()
+	}
+	discount := object{
+		"object":   "discount",
+		"coupon":   copyObject(coupon),
+		"customer": customer["id"],
+		"start":    start,
+		"end":      end,
+	}
+	customer["discount"] = discount
+	s.record("customer.discount.created", discount)
+	return nil
+}
+
+func (s *Server) getCoupon(r *http.Request) (interface{}, *apiError) {
+	coupon, ok := s.coupons[r.PathValue("id")]
+	if !ok {
+		return nil, noSuch("coupon", r.PathValue("id"))
+	}
+	return coupon, nil
+}
+
+func (s *Server) deleteCoupon(r *http.Request) (interface{}, *apiError) {
+	id := r.PathValue("id")
+	coupon, ok := s.coupons[id]
+	if !ok {
+		return nil, noSuch("coupon", id)
+	}
+	delete(s.coupons, id)
+	s.record("coupon.deleted", coupon)
+	return deleted(id), nil
+}
+
+func (s *Server) listCoupons(r *http.Request) (interface{}, *apiError) {
+	return list(r, s.coupons, nil)
+}
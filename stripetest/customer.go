@@ -0,0 +1,116 @@
+package stripetest
+
+import (
+	"net/http"
+)
+
+func (s *Server) createCustomer(r *http.Request) (interface{}, *apiError) {
+	balance, _, apiErr := intParam(r, "account_balance")
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	md, apiErr := metadata(r, nil)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	card, apiErr := s.customerCard(r)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	customer := object{
+		"id":              s.newID("cus"),
+		"object":          "customer",
+		"livemode":        false,
+		"created":         s.now(),
+		"description":     optional(r, "description"),
+		"email":           optional(r, "email"),
+		"account_balance": balance,
+		"delinquent":      false,
+		"active_card":     card,
+		"discount":        nil,
+		"subscription":    nil,
+		"metadata":        md,
+	}
+	var sub object
+	if plan := r.Form.Get("plan"); plan != "" {
+		sub, apiErr = s.newSubscription(r, customer, plan)
+		if apiErr != nil {
+			return nil, apiErr
+		}
+	}
+	if coupon := r.Form.Get("coupon"); coupon != "" {
+		if apiErr := s.applyCoupon(customer, coupon); apiErr != nil {
+			return nil, apiErr
+		}
+	}
+	s.customers[customer["id"].(string)] = customer
+	s.record("customer.created", customer)
+	if sub != nil {
+		if apiErr := s.startSubscription(customer, sub); apiErr != nil {
+			return nil, apiErr
+		}
+	}
+	return customer, nil
+}
+
+func (s *Server) getCustomer(r *http.Request) (interface{}, *apiError) {
+	customer, ok := s.customers[r.PathValue("id")]
+	if !ok {
+		return nil, noSuch("customer", r.PathValue("id"))
+	}
+	return customer, nil
+}
+
+func (s *Server) updateCustomer(r *http.Request) (interface{}, *apiError) {
+	customer, ok := s.customers[r.PathValue("id")]
+	if !ok {
+		return nil, noSuch("customer", r.PathValue("id"))
+	}
+	md, apiErr := metadata(r, customer["metadata"].(map[string]string))
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	card, apiErr := s.customerCard(r)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	if coupon := r.Form.Get("coupon"); coupon != "" {
+		if apiErr := s.applyCoupon(customer, coupon); apiErr != nil {
+			return nil, apiErr
+		}
+	}
+	if card != nil {
+		customer["active_card"] = card
+	}
+	customer["metadata"] = md
+	for _, key := range []string{"description", "email"} {
+		if _, ok := r.Form[key]; ok {
+			customer[key] = optional(r, key)
+		}
+	}
+	if balance, ok, apiErr := intParam(r, "account_balance"); apiErr != nil {
+		return nil, apiErr
+	} else if ok {
+		customer["account_balance"] = balance
+	}
+	s.record("customer.updated", customer)
+	return customer, nil
+}
+
+func (s *Server) deleteCustomer(r *http.Request) (interface{}, *apiError) {
+	id := r.PathValue("id")
+	customer, ok := s.customers[id]
+	if !ok {
+		return nil, noSuch("customer", id)
+	}
+	if sub, ok := customer["subscription"].(object); ok {
+		s.endSubscription(customer, sub)
+	}
+	delete(s.customers, id)
+	s.record("customer.deleted", customer)
+	return deleted(id), nil
+}
+
+func (s *Server) listCustomers(r *http.Request) (interface{}, *apiError) {
+	return list(r, s.customers, nil)
+}
@@ -0,0 +1,234 @@
+package stripetest
+
+import (
+	"net/http"
+	"strings"
+)
+
+// draftInvoice returns an invoice for customer's subscription sub, and for
+// the customer's pending invoice items, without storing it. It also returns
+// the customer's account balance after the invoice is paid.
+func (s *Server) draftInvoice(customer, sub object) (invoice object, ending int64) {
+	var lines []object
+	var subtotal int64
+	items := []object{}
+	for _, item := range s.invoiceItems {
+		if item["customer"] == customer["id"] && item["invoice"] == nil {
+			items = append(items, item)
+			subtotal += item["amount"].(int64)
+		}
+	}
+	start, end := s.now(), s.now()
+	if sub != nil {
+		plan := sub["plan"].(object)
+		amount := int64(plan["amount"].(float64))
+		start = sub["current_period_start"].(int64)
+		end = sub["current_period_end"].(int64)
+		lines = append(lines, object{
+			"amount": amount,
+			"period": object{"start": start, "end": end},
+			"plan":   plan,
+		})
+		subtotal += amount
+	}
+	total := subtotal
+	discount, _ := customer["discount"].(object)
+	if discount != nil {
+		percentOff := int64(discount["coupon"].(object)["percent_off"].(float64))
+		total -= subtotal * percentOff / 100
+	}
+	balance := customer["account_balance"].(int64)
+	due := total + balance
+	if due < 0 {
+		ending, due = due, 0
+	}
+	if lines == nil {
+		lines = []object{}
+	}
+	return object{
+		"object":               "invoice",
+		"livemode":             false,
+		"customer":             customer["id"],
+		"date":                 s.now(),
+		"period_start":         start,
+		"period_end":           end,
+		"subtotal":             subtotal,
+		"total":                total,
+		"amount_due":           due,
+		"starting_balance":     balance,
+		"ending_balance":       ending,
+		"discount":             discount,
+		"attempted":            false,
+		"attempt_count":        int64(0),
+		"closed":               false,
+		"paid":                 false,
+		"charge":               nil,
+		"next_payment_attempt": nil,
+		"lines": object{
+			"subscriptions": lines,
+			"invoiceitems":  items,
+			"prorations":    []object{},
+		},
+	}, ending
+}
+
+// bill invoices customer for the current period of sub, and charges the
+// customer's active card for it.
+func (s *Server) bill(customer, sub object) *apiError {
+	invoice, ending := s.draftInvoice(customer, sub)
+	id := s.newID("in")
+	invoice["id"] = id
+	for _, item := range invoice["lines"].(object)["invoiceitems"].([]object) {
+		item["invoice"] = id
+	}
+	customer["account_balance"] = ending
+	s.invoices[id] = invoice
+	s.record("invoice.created", invoice)
+
+	invoice["attempted"] = true
+	invoice["attempt_count"] = int64(1)
+	invoice["closed"] = true
+	if due := invoice["amount_due"].(int64); due > 0 {
+		card, _ := customer["active_card"].(object)
+		if card == nil {
+			return cardError("missing", "card", "This customer has no attached card")
+		}
+		plan := sub["plan"].(object)
+		currency := strings.ToLower(plan["currency"].(string))
+		charge, apiErr := s.charge(due, currency, card, customer["id"], nil, true)
+		if apiErr != nil {
+			invoice["closed"] = false
+			customer["delinquent"] = true
+			s.record("invoice.payment_failed", invoice)
+			return apiErr
+		}
+		invoice["charge"] = charge["id"]
+	}
+	invoice["paid"] = true
+	s.record("invoice.payment_succeeded", invoice)
+	return nil
+}
+
+func (s *Server) getInvoice(r *http.Request) (interface{}, *apiError) {
+	invoice, ok := s.invoices[r.PathValue("id")]
+	if !ok {
+		return nil, noSuch("invoice", r.PathValue("id"))
+	}
+	return invoice, nil
+}
+
+func (s *Server) upcomingInvoice(r *http.Request) (interface{}, *apiError) {
+	id := r.Form.Get("customer")
+	if id == "" {
+		return nil, missingParam("customer")
+	}
+	customer, ok := s.customers[id]
+	if !ok {
+		return nil, noSuch("customer", id)
+	}
+	sub, _ := customer["subscription"].(object)
+	if sub != nil {
+		// The next invoice is for the period after the current one.
+		sub = copyObject(sub)
+		start := int64(sub["current_period_end"].(float64))
+		sub["current_period_start"] = start
+		sub["current_period_end"] = periodEnd(start, sub["plan"].(object)["interval"].(string))
+		if sub["cancel_at_period_end"] == true {
+			sub = nil
+		}
+	}
+	invoice, _ := s.draftInvoice(customer, sub)
+	if sub == nil && len(invoice["lines"].(object)["invoiceitems"].([]object)) == 0 {
+		return nil, &apiError{Status: 404, Type: "invalid_request_error", Message: "No upcoming invoices for customer: " + id}
+	}
+	invoice["date"] = invoice["period_start"]
+	invoice["next_payment_attempt"] = invoice["period_start"]
+	return invoice, nil
+}
+
+func (s *Server) listInvoices(r *http.Request) (interface{}, *apiError) {
+	return list(r, s.invoices, byCustomer(r))
+}
+
+func (s *Server) createInvoiceItem(r *http.Request) (interface{}, *apiError) {
+	for _, key := range []string{"customer", "amount", "currency"} {
+		if r.Form.Get(key) == "" {
+			return nil, missingParam(key)
+		}
+	}
+	customer := r.Form.Get("customer")
+	if _, ok := s.customers[customer]; !ok {
+		return nil, noSuch("customer", customer)
+	}
+	amount, _, apiErr := intParam(r, "amount")
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	md, apiErr := metadata(r, nil)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	item := object{
+		"id":          s.newID("ii"),
+		"object":      "invoiceitem",
+		"livemode":    false,
+		"date":        s.now(),
+		"customer":    customer,
+		"amount":      amount,
+		"currency":    strings.ToLower(r.Form.Get("currency")),
+		"description": optional(r, "description"),
+		"invoice":     nil,
+		"metadata":    md,
+	}
+	s.invoiceItems[item["id"].(string)] = item
+	s.record("invoiceitem.created", item)
+	return item, nil
+}
+
+func (s *Server) getInvoiceItem(r *http.Request) (interface{}, *apiError) {
+	item, ok := s.invoiceItems[r.PathValue("id")]
+	if !ok {
+		return nil, noSuch("invoiceitem", r.PathValue("id"))
+	}
+	return item, nil
+}
+
+func (s *Server) updateInvoiceItem(r *http.Request) (interface{}, *apiError) {
+	item, ok := s.invoiceItems[r.PathValue("id")]
+	if !ok {
+		return nil, noSuch("invoiceitem", r.PathValue("id"))
+	}
+	if item["invoice"] != nil {
+		return nil, invalidRequest("id", "Invoice item "+item["id"].(string)+" has already been invoiced.")
+	}
+	md, apiErr := metadata(r, item["metadata"].(map[string]string))
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	if amount, ok, apiErr := intParam(r, "amount"); apiErr != nil {
+		return nil, apiErr
+	} else if ok {
+		item["amount"] = amount
+	}
+	item["metadata"] = md
+	if _, ok := r.Form["description"]; ok {
+		item["description"] = optional(r, "description")
+	}
+	s.record("invoiceitem.updated", item)
+	return item, nil
+}
+
+func (s *Server) deleteInvoiceItem(r *http.Request) (interface{}, *apiError) {
+	id := r.PathValue("id")
+	item, ok := s.invoiceItems[id]
+	if !ok {
+		return nil, noSuch("invoiceitem", id)
+	}
+	delete(s.invoiceItems, id)
+	s.record("invoiceitem.deleted", item)
+	return deleted(id), nil
+}
+
+func (s *Server) listInvoiceItems(r *http.Request) (interface{}, *apiError) {
+	return list(r, s.invoiceItems, byCustomer(r))
+}
@@ -0,0 +1,96 @@
+package stripetest
+
+import (
+	"net/http"
+	"strings"
+)
+
+func (s *Server) createPlan(r *http.Request) (interface{}, *apiError) {
+	for _, key := range []string{"id", "amount", "currency", "interval", "name"} {
+		if r.Form.Get(key) == "" {
+			return nil, missingParam(key)
+		}
+	}
+	id := r.Form.Get("id")
+	if _, ok := s.plans[id]; ok {
+		return nil, invalidRequest("id", "Plan already exists.")
+	}
+	amount, _, apiErr := intParam(r, "amount")
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	if amount < 0 {
+		return nil, invalidRequest("amount", "Invalid amount: must be at least 0")
+	}
+	interval := r.Form.Get("interval")
+	switch interval {
+	case "day", "week", "month", "year":
+	default:
+		return nil, invalidRequest("interval", "Invalid interval: must be one of day, week, month or year")
+	}
+	md, apiErr := metadata(r, nil)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	var trialDays interface{}
+	if days, ok, apiErr := intParam(r, "trial_period_days"); apiErr != nil {
+		return nil, apiErr
+	} else if ok {
+		trialDays = days
+	}
+	plan := object{
+		"id":                id,
+		"object":            "plan",
+		"livemode":          false,
+		"created":           s.now(),
+		"amount":            amount,
+		"currency":          strings.ToLower(r.Form.Get("currency")),
+		"interval":          interval,
+		"name":              r.Form.Get("name"),
+		"trial_period_days": trialDays,
+		"metadata":          md,
+	}
+	s.plans[id] = plan
+	s.record("plan.created", plan)
+	return plan, nil
+}
+
+func (s *Server) getPlan(r *http.Request) (interface{}, *apiError) {
+	plan, ok := s.plans[r.PathValue("id")]
+	if !ok {
+		return nil, noSuch("plan", r.PathValue("id"))
+	}
+	return plan, nil
+}
+
+func (s *Server) updatePlan(r *http.Request) (interface{}, *apiError) {
+	plan, ok := s.plans[r.PathValue("id")]
+	if !ok {
+		return nil, noSuch("plan", r.PathValue("id"))
+	}
+	md, apiErr := metadata(r, plan["metadata"].(map[string]string))
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	if name := r.Form.Get("name"); name != "" {
+		plan["name"] = name
+	}
+	plan["metadata"] = md
+	s.record("plan.updated", plan)
+	return plan, nil
+}
+
+func (s *Server) deletePlan(r *http.Request) (interface{}, *apiError) {
+	id := r.PathValue("id")
+	plan, ok := s.plans[id]
+	if !ok {
+		return nil, noSuch("plan", id)
+	}
+	delete(s.plans, id)
+	s.record("plan.deleted", plan)
+	return deleted(id), nil
+}
+
+func (s *Server) listPlans(r *http.Request) (interface{}, *apiError) {
+	return list(r, s.plans, nil)
+}
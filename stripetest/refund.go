@@ -0,0 +1,105 @@
+package stripetest
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// refundRequest refunds the charge in r's path by the amount in its form, or
+// by all of the charge that hasn't been refunded, and returns the charge and
+// the new refund.
+func (s *Server) refundRequest(r *http.Request) (charge, refund object, apiErr *apiError) {
+	charge, ok := s.charges[r.PathValue("id")]
+	if !ok {
+		return nil, nil, noSuch("charge", r.PathValue("id"))
+	}
+	if charge["paid"] != true {
+		return nil, nil, invalidRequest("id", "Charge "+charge["id"].(string)+" has not been paid, so it cannot be refunded.")
+	}
+	remaining := charge["amount"].(int64) - charge["amount_refunded"].(int64)
+	if remaining == 0 {
+		return nil, nil, invalidRequest("amount", "Charge "+charge["id"].(string)+" has already been refunded.")
+	}
+	amount, ok, apiErr := intParam(r, "amount")
+	if apiErr != nil {
+		return nil, nil, apiErr
+	}
+	if !ok {
+		amount = remaining
+	}
+	if charge["captured"] != true && amount != remaining {
+		// Refunding an uncaptured charge releases its hold, which can't be
+		// done in part.
+		return nil, nil, invalidRequest("amount", "Charge "+charge["id"].(string)+" has not been captured, so it can only be refunded in full.")
+	}
+	if amount <= 0 {
+		return nil, nil, invalidRequest("amount", "Refund amount must be positive.")
+	}
+	if amount > remaining {
+		return nil, nil, invalidRequest("amount", fmt.Sprintf("Refund amount ($%.2f) is greater than unrefunded amount on charge ($%.2f)", float64(amount)/100, float64(remaining)/100))
+	}
+	reason := optional(r, "reason")
+	switch reason {
+	case nil, "duplicate", "fraudulent", "requested_by_customer":
+	default:
+		return nil, nil, invalidRequest("reason", "Invalid reason: must be one of duplicate, fraudulent, or requested_by_customer")
+	}
+	md, apiErr := metadata(r, nil)
+	if apiErr != nil {
+		return nil, nil, apiErr
+	}
+	refund = s.refund(charge, amount, reason, md)
+	s.record("charge.refunded", charge)
+	return charge, refund, nil
+}
+
+// refund refunds amount of charge, and returns the new refund.
+func (s *Server) refund(charge object, amount int64, reason interface{}, md map[string]string) object {
+	if md == nil {
+		md = map[string]string{}
+	}
+	refund := object{
+		"id":       s.newID("re"),
+		"object":   "refund",
+		"created":  s.now(),
+		"amount":   amount,
+		"currency": charge["currency"],
+		"charge":   charge["id"],
+		"reason":   reason,
+		"metadata": md,
+	}
+	s.refunds[refund["id"].(string)] = refund
+	charge["amount_refunded"] = charge["amount_refunded"].(int64) + amount
+	charge["refunded"] = charge["amount_refunded"] == charge["amount"]
+	// A charge's refunds are listed newest first.
+	refunds := charge["refunds"].(object)
+	refunds["data"] = append([]object{refund}, refunds["data"].([]object)...)
+	refunds["count"] = len(refunds["data"].([]object))
+	return refund
+}
+
+func (s *Server) createRefund(r *http.Request) (interface{}, *apiError) {
+	_, refund, apiErr := s.refundRequest(r)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	return refund, nil
+}
+
+func (s *Server) getRefund(r *http.Request) (interface{}, *apiError) {
+	refund, ok := s.refunds[r.PathValue("id")]
+	if !ok || refund["charge"] != r.PathValue("charge") {
+		return nil, noSuch("refund", r.PathValue("id"))
+	}
+	return refund, nil
+}
+
+func (s *Server) listRefunds(r *http.Request) (interface{}, *apiError) {
+	id := r.PathValue("id")
+	if _, ok := s.charges[id]; !ok {
+		return nil, noSuch("charge", id)
+	}
+	return list(r, s.refunds, func(refund object) bool {
+		return refund["charge"] == id
+	})
+}
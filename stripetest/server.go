@@ -0,0 +1,450 @@
+// Package stripetest provides an in-memory emulation of the Stripe API, for
+// testing code that uses package stripe without network access or an API key.
+//
+//	server := stripetest.NewServer()
+//	defer server.Close()
+//	API := stripe.New("sk_test_anything", stripe.WithBaseURL(server.BaseURL()))
+//
+// The emulator covers tokens, charges (captured at once, or authorized and
+// captured later) and refunds, customers, plans, coupons, subscriptions,
+// invoices, invoice items, and events, and responds with the same JSON, status
+// codes, and error envelopes Stripe does. Every request must authenticate with
+// a non-empty API key, as with Stripe.
+package stripetest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// object is a Stripe API object, as it is encoded to JSON.
+type object = map[string]interface{}
+
+// Server is an httptest.Server emulating the Stripe API.
+type Server struct {
+	*httptest.Server
+
+	// Now returns the time used for the creation timestamps of new objects, and
+	// for anything else that depends on the time. It defaults to time.Now, and
+	// can be replaced to control the passage of time in tests.
+	Now func() time.Time
+
+	mu           sync.Mutex
+	seq          int
+	tokens       map[string]object
+	cardNumbers  map[string]string // card ID to card number
+	charges      map[string]object
+	refunds      map[string]object
+	customers    map[string]object
+	plans        map[string]object
+	coupons      map[string]object
+	invoices     map[string]object
+	invoiceItems map[string]object
+	events       map[string]object
+}
+
+// NewServer starts and returns a new, empty *Server. The caller should call
+// Close when finished, to shut it down.
+func NewServer() *Server {
+	s := &Server{
+		Now:          time.Now,
+		tokens:       make(map[string]object),
+		cardNumbers:  make(map[string]string),
+		charges:      make(map[string]object),
+		refunds:      make(map[string]object),
+		customers:    make(map[string]object),
+		plans:        make(map[string]object),
+		coupons:      make(map[string]object),
+		invoices:     make(map[string]object),
+		invoiceItems: make(map[string]object),
+		events:       make(map[string]object),
+	}
+	routes := map[string]handlerFunc{
+		"POST /v1/tokens":                        s.createToken,
+		"GET /v1/tokens/{id}":                    s.getToken,
+		"POST /v1/charges":                       s.createCharge,
+		"GET /v1/charges":                        s.listCharges,
+		"GET /v1/charges/{id}":                   s.getCharge,
+		"POST /v1/charges/{id}":                  s.updateCharge,
+		"POST /v1/charges/{id}/refund":           s.refundCharge,
+		"POST /v1/charges/{id}/capture":          s.captureCharge,
+		"POST /v1/charges/{id}/refunds":          s.createRefund,
+		"GET /v1/charges/{id}/refunds":           s.listRefunds,
+		"GET /v1/charges/{charge}/refunds/{id}":  s.getRefund,
+		"POST /v1/customers":                     s.createCustomer,
+		"GET /v1/customers":                      s.listCustomers,
+		"GET /v1/customers/{id}":                 s.getCustomer,
+		"POST /v1/customers/{id}":                s.updateCustomer,
+		"DELETE /v1/customers/{id}":              s.deleteCustomer,
+		"POST /v1/customers/{id}/subscription":   s.subscribe,
+		"DELETE /v1/customers/{id}/subscription": s.unsubscribe,
+		"POST /v1/plans":                         s.createPlan,
+		"GET /v1/plans":                          s.listPlans,
+		"GET /v1/plans/{id}":                     s.getPlan,
+		"POST /v1/plans/{id}":                    s.updatePlan,
+		"DELETE /v1/plans/{id}":                  s.deletePlan,
+		"POST /v1/coupons":                       s.createCoupon,
+		"GET /v1/coupons":                        s.listCoupons,
+		"GET /v1/coupons/{id}":                   s.getCoupon,
+		"DELETE /v1/coupons/{id}":                s.deleteCoupon,
+		"GET /v1/invoices":                       s.listInvoices,
+		"GET /v1/invoices/upcoming":              s.upcomingInvoice,
+		"GET /v1/invoices/{id}":                  s.getInvoice,
+		"POST /v1/invoiceitems":                  s.createInvoiceItem,
+		"GET /v1/invoiceitems":                   s.listInvoiceItems,
+		"GET /v1/invoiceitems/{id}":              s.getInvoiceItem,
+		"POST /v1/invoiceitems/{id}":             s.updateInvoiceItem,
+		"DELETE /v1/invoiceitems/{id}":           s.deleteInvoiceItem,
+		"GET /v1/events":                         s.listEvents,
+		"GET /v1/events/{id}":                    s.getEvent,
+	}
+	var table []route
+	for pattern, handler := range routes {
+		method, path, _ := strings.Cut(pattern, " ")
+		table = append(table, route{method: method, segments: splitPath(path), handler: s.serve(handler)})
+	}
+	notFound := s.serve(func(r *http.Request) (interface{}, *apiError) {
+		return nil, &apiError{Status: 404, Type: "invalid_request_error", Message: "Unrecognized request URL (" + r.Method + ": " + r.URL.Path + ")."}
+	})
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if handler := dispatch(table, r); handler != nil {
+			handler.ServeHTTP(w, r)
+			return
+		}
+		notFound.ServeHTTP(w, r)
+	}))
+	return s
+}
+
+// route is a method and path the Server handles. A segment of the path in
+// braces, like {id}, matches any single segment, and its value is available to
+// the handler from r.PathValue. Routing is done here rather than by
+// http.ServeMux so that it doesn't depend on the GODEBUG settings that decide
+// whether ServeMux understands methods and wildcards in its patterns.
+type route struct {
+	method   string
+	segments []string
+	handler  http.Handler
+}
+
+func splitPath(path string) []string {
+	return strings.Split(strings.Trim(path, "/"), "/")
+}
+
+func isWildcard(segment string) bool {
+	return strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}")
+}
+
+// dispatch returns the handler of the route matching r, after setting r's path
+// values for the route's wildcards, or nil if no route matches. A route with
+// fewer wildcards takes precedence, so GET /v1/invoices/upcoming isn't taken
+// for the invoice with an ID of "upcoming".
+func dispatch(table []route, r *http.Request) http.Handler {
+	segments := splitPath(r.URL.Path)
+	var best *route
+	bestWildcards := 0
+	for i := range table {
+		rt := &table[i]
+		if rt.method != r.Method || len(rt.segments) != len(segments) {
+			continue
+		}
+		wildcards, ok := 0, true
+		for j, segment := range rt.segments {
+			if isWildcard(segment) {
+				wildcards++
+			} else if segment != segments[j] {
+				ok = false
+				break
+			}
+		}
+		if ok && (best == nil || wildcards < bestWildcards) {
+			best, bestWildcards = rt, wildcards
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	for j, segment := range best.segments {
+		if isWildcard(segment) {
+			r.SetPathValue(segment[1:len(segment)-1], segments[j])
+		}
+	}
+	return best.handler
+}
+
+// BaseURL returns the URL to pass to stripe.WithBaseURL to use the Server.
+func (s *Server) BaseURL() string {
+	return s.URL + "/v1/"
+}
+
+// apiError is an error response, encoded the way Stripe encodes them.
+type apiError struct {
+	Status      int    `json:"-"`
+	Type        string `json:"type"`
+	Code        string `json:"code,omitempty"`
+	Param       string `json:"param,omitempty"`
+	Message     string `json:"message"`
+	DeclineCode string `json:"decline_code,omitempty"`
+	Charge      string `json:"charge,omitempty"`
+}
+
+func invalidRequest(param, message string) *apiError {
+	return &apiError{Status: 400, Type: "invalid_request_error", Param: param, Message: message}
+}
+
+func missingParam(param string) *apiError {
+	return invalidRequest(param, "Missing required param: "+param+".")
+}
+
+func noSuch(kind, id string) *apiError {
+	return &apiError{Status: 404, Type: "invalid_request_error", Param: "id", Message: "No such " + kind + ": " + id}
+}
+
+func cardError(code, param, message string) *apiError {
+	return &apiError{Status: 402, Type: "card_error", Code: code, Param: param, Message: message}
+}
+
+type handlerFunc func(r *http.Request) (interface{}, *apiError)
+
+// serve authenticates the request, runs handler with the Server locked, and
+// writes its response.
+func (s *Server) serve(handler handlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		var resp interface{}
+		var apiErr *apiError
+		key, _, ok := r.BasicAuth()
+		if !ok || key == "" {
+			apiErr = &apiError{Status: 401, Type: "invalid_request_error", Message: "You did not provide an API key."}
+		} else if err := r.ParseForm(); err != nil {
+			apiErr = invalidRequest("", "Invalid request body.")
+		} else {
+			s.expireHolds()
+			resp, apiErr = handler(r)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Request-Id", s.newID("req"))
+		if apiErr != nil {
+			w.WriteHeader(apiErr.Status)
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": apiErr})
+			return
+		}
+		json.NewEncoder(w).Encode(resp)
+	})
+}
+
+// newID returns a new, unique ID for an object, beginning with prefix.
+func (s *Server) newID(prefix string) string {
+	s.seq++
+	return fmt.Sprintf("%s_%014d", prefix, s.seq)
+}
+
+func (s *Server) now() int64 {
+	return s.Now().Unix()
+}
+
+// copyObject returns a deep copy of obj, as it would be decoded from JSON.
+func copyObject(obj object) object {
+	data, _ := json.Marshal(obj)
+	var c object
+	json.Unmarshal(data, &c)
+	return c
+}
+
+// record adds an Event of eventType about obj to the Server's events.
+func (s *Server) record(eventType string, obj object) {
+	id := s.newID("evt")
+	s.events[id] = object{
+		"id":               id,
+		"object":           "event",
+		"type":             eventType,
+		"created":          s.now(),
+		"livemode":         false,
+		"pending_webhooks": 0,
+		"data":             object{"object": copyObject(obj)},
+	}
+}
+
+func deleted(id string) object {
+	return object{"id": id, "deleted": true}
+}
+
+// intParam parses the integer form value key. It returns ok false if the value
+// is absent.
+func intParam(r *http.Request, key string) (value int64, ok bool, apiErr *apiError) {
+	raw := r.Form.Get(key)
+	if raw == "" {
+		return 0, false, nil
+	}
+	value, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return 0, false, invalidRequest(key, "Invalid integer: "+raw)
+	}
+	return value, true, nil
+}
+
+// optional returns the form value key, or nil if it is empty, for the
+// properties of objects that Stripe reports as null when unset.
+func optional(r *http.Request, key string) interface{} {
+	if v := r.Form.Get(key); v != "" {
+		return v
+	}
+	return nil
+}
+
+// metadata returns current updated by the request's metadata[key] parameters,
+// where an empty value removes the key, after checking Stripe's limits.
+func metadata(r *http.Request, current map[string]string) (map[string]string, *apiError) {
+	updated := make(map[string]string)
+	for key, value := range current {
+		updated[key] = value
+	}
+	for param := range r.Form {
+		key, ok := strings.CutPrefix(param, "metadata[")
+		if !ok || !strings.HasSuffix(key, "]") {
+			continue
+		}
+		key = strings.TrimSuffix(key, "]")
+		value := r.Form.Get(param)
+		switch {
+		case key == "":
+			return nil, invalidRequest("metadata", "Metadata keys must not be empty.")
+		case utf8.RuneCountInString(key) > 40:
+			return nil, invalidRequest(param, "Metadata keys can have a maximum length of 40 characters.")
+		case utf8.RuneCountInString(value) > 500:
+			return nil, invalidRequest(param, "Metadata values can have a maximum length of 500 characters.")
+		case value == "":
+			delete(updated, key)
+		default:
+			updated[key] = value
+		}
+	}
+	if len(updated) > 50 {
+		return nil, invalidRequest("metadata", "Metadata can have a maximum of 50 keys.")
+	}
+	return updated, nil
+}
+
+// list responds with the objects in objs that match, newest first, paged by
+// the request's count and offset parameters.
+func list(r *http.Request, objs map[string]object, match func(object) bool) (interface{}, *apiError) {
+	count, ok, apiErr := intParam(r, "count")
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	if !ok {
+		count = 10
+	}
+	if count < 1 || count > 100 {
+		return nil, invalidRequest("count", "Invalid count: must be between 1 and 100")
+	}
+	offset, _, apiErr := intParam(r, "offset")
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	if offset < 0 {
+		return nil, invalidRequest("offset", "Invalid offset: must be at least 0")
+	}
+	var matched []object
+	for _, obj := range objs {
+		if match == nil || match(obj) {
+			matched = append(matched, obj)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		ci, cj := created(matched[i]), created(matched[j])
+		if ci != cj {
+			return ci > cj
+		}
+		return matched[i]["id"].(string) > matched[j]["id"].(string)
+	})
+	data := []object{}
+	for i := int(offset); i < len(matched) && i < int(offset+count); i++ {
+		data = append(data, matched[i])
+	}
+	return object{
+		"object": "list",
+		"url":    r.URL.Path,
+		"count":  len(matched),
+		"data":   data,
+	}, nil
+}
+
+func created(obj object) int64 {
+	for _, key := range []string{"created", "date"} {
+		if v, ok := obj[key].(int64); ok {
+			return v
+		}
+	}
+	return 0
+}
+
+func byCustomer(r *http.Request) func(object) bool {
+	customer := r.Form.Get("customer")
+	if customer == "" {
+		return nil
+	}
+	return func(obj object) bool {
+		return obj["customer"] == customer
+	}
+}
+
+func (s *Server) getEvent(r *http.Request) (interface{}, *apiError) {
+	event, ok := s.events[r.PathValue("id")]
+	if !ok {
+		return nil, noSuch("event", r.PathValue("id"))
+	}
+	return event, nil
+}
+
+func (s *Server) listEvents(r *http.Request) (interface{}, *apiError) {
+	eventType := r.Form.Get("type")
+	bounds := map[string]func(a, b int64) bool{
+		"created":      func(a, b int64) bool { return a == b },
+		"created[gt]":  func(a, b int64) bool { return a > b },
+		"created[gte]": func(a, b int64) bool { return a >= b },
+		"created[lt]":  func(a, b int64) bool { return a < b },
+		"created[lte]": func(a, b int64) bool { return a <= b },
+	}
+	var filters []func(int64) bool
+	for key, compare := range bounds {
+		bound, ok, apiErr := intParam(r, key)
+		if apiErr != nil {
+			return nil, apiErr
+		}
+		if ok {
+			compare := compare
+			filters = append(filters, func(created int64) bool { return compare(created, bound) })
+		}
+	}
+	return list(r, s.events, func(event object) bool {
+		if eventType != "" && !matchType(eventType, event["type"].(string)) {
+			return false
+		}
+		for _, filter := range filters {
+			if !filter(created(event)) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+func matchType(pattern, eventType string) bool {
+	if pattern == "*" {
+		return true
+	}
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		return strings.HasPrefix(eventType, prefix)
+	}
+	return pattern == eventType
+}
@@ -0,0 +1,117 @@
+package stripetest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func do(t *testing.T, s *Server, method, path, key string, form url.Values) (int, map[string]interface{}) {
+	t.Helper()
+	req, err := http.NewRequest(method, s.BaseURL()+path, strings.NewReader(form.Encode()))
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if key != "" {
+		req.SetBasicAuth(key, "")
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	defer resp.Body.Close()
+	var body map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	return resp.StatusCode, body
+}
+
+func TestErrors(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	tests := []struct {
+		method, path, key string
+		status            int
+		errType           string
+	}{
+		{"GET", "charges", "", 401, "invalid_request_error"},
+		{"GET", "charges/ch_missing", "sk_test_key", 404, "invalid_request_error"},
+		{"GET", "nowhere", "sk_test_key", 404, "invalid_request_error"},
+		{"POST", "charges", "sk_test_key", 400, "invalid_request_error"},
+		{"GET", "charges?count=101", "sk_test_key", 400, "invalid_request_error"},
+	}
+	for _, test := range tests {
+		status, body := do(t, s, test.method, test.path, test.key, nil)
+		if status != test.status {
+			t.Errorf("%s %s: status is %v, expected %v", test.method, test.path, status, test.status)
+		}
+		raw, _ := body["error"].(map[string]interface{})
+		if raw["type"] != test.errType {
+			t.Errorf("%s %s: error is %v, expected a %v", test.method, test.path, body["error"], test.errType)
+		}
+	}
+}
+
+func TestListPaging(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	var ids []interface{}
+	for i := 0; i < 5; i++ {
+		status, customer := do(t, s, "POST", "customers", "sk_test_key", nil)
+		if status != 200 {
+			t.Fatalf("status is %v, expected %v", status, 200)
+		}
+		ids = append(ids, customer["id"])
+	}
+	_, body := do(t, s, "GET", "customers?count=2&offset=3", "sk_test_key", nil)
+	if body["count"] != 5.0 {
+		t.Errorf("count is %v, expected %v", body["count"], 5)
+	}
+	data, _ := body["data"].([]interface{})
+	if len(data) != 2 {
+		t.Fatalf("len(data) is %v, expected %v", len(data), 2)
+	}
+	// Newest first, so skipping three leaves the second and first customers created.
+	for i, want := range []interface{}{ids[1], ids[0]} {
+		if id := data[i].(map[string]interface{})["id"]; id != want {
+			t.Errorf("data[%d] is %v, expected %v", i, id, want)
+		}
+	}
+}
+
+func TestCardChecks(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	tests := []struct {
+		number                         string
+		cvcCheck, line1Check, zipCheck interface{}
+	}{
+		{CardSuccess, "pass", "pass", "pass"},
+		{CardCVCCheckFails, "fail", "pass", "pass"},
+		{CardAddressLine1CheckFails, "pass", "fail", "pass"},
+		{CardZipCheckFails, "pass", "pass", "fail"},
+		{CardChecksUnavailable, "pass", "unavailable", "unavailable"},
+	}
+	for _, test := range tests {
+		form := url.Values{
+			"card[number]":        {test.number},
+			"card[exp_month]":     {"12"},
+			"card[exp_year]":      {"2099"},
+			"card[cvc]":           {"123"},
+			"card[address_line1]": {"123 Awesome Street"},
+			"card[address_zip]":   {"12345"},
+		}
+		status, token := do(t, s, "POST", "tokens", "sk_test_key", form)
+		if status != 200 {
+			t.Fatalf("%v: status is %v, expected %v", test.number, status, 200)
+		}
+		card := token["card"].(map[string]interface{})
+		if card["cvc_check"] != test.cvcCheck || card["address_line1_check"] != test.line1Check || card["address_zip_check"] != test.zipCheck {
+			t.Errorf("%v: checks are %v, %v, %v, expected %v, %v, %v", test.number, card["cvc_check"], card["address_line1_check"], card["address_zip_check"], test.cvcCheck, test.line1Check, test.zipCheck)
+		}
+	}
+}
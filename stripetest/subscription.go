@@ -0,0 +1,149 @@
+package stripetest
+
+import (
+	"net/http"
+	"time"
+)
+
+// periodEnd returns the end of the billing period for interval beginning at start.
+func periodEnd(start int64, interval string) int64 {
+	t := time.Unix(start, 0).UTC()
+	switch interval {
+	case "day":
+		t = t.AddDate(0, 0, 1)
+	case "week":
+		t = t.AddDate(0, 0, 7)
+	case "year":
+		t = t.AddDate(1, 0, 0)
+	default:
+		t = t.AddDate(0, 1, 0)
+	}
+	return t.Unix()
+}
+
+// newSubscription creates a subscription of customer to the plan with an ID
+// of planID, without yet starting it.
+func (s *Server) newSubscription(r *http.Request, customer object, planID string) (object, *apiError) {
+	plan, ok := s.plans[planID]
+	if !ok {
+		return nil, noSuch("plan", planID)
+	}
+	md, apiErr := metadata(r, nil)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	now := s.now()
+	trialEnd, ok, apiErr := intParam(r, "trial_end")
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	if !ok {
+		if days := plan["trial_period_days"]; days != nil {
+			trialEnd = time.Unix(now, 0).AddDate(0, 0, int(days.(int64))).Unix()
+		}
+	} else if trialEnd <= now {
+		return nil, invalidRequest("trial_end", "Invalid timestamp: must be an integer Unix timestamp in the future.")
+	}
+	sub := object{
+		"object":               "subscription",
+		"customer":             customer["id"],
+		"plan":                 copyObject(plan),
+		"status":               "active",
+		"start":                now,
+		"current_period_start": now,
+		"current_period_end":   periodEnd(now, plan["interval"].(string)),
+		"cancel_at_period_end": false,
+		"canceled_at":          nil,
+		"ended_at":             nil,
+		"trial_start":          nil,
+		"trial_end":            nil,
+		"metadata":             md,
+	}
+	if trialEnd > 0 {
+		sub["status"] = "trialing"
+		sub["trial_start"] = now
+		sub["trial_end"] = trialEnd
+		sub["current_period_end"] = trialEnd
+	} else if plan["amount"].(int64) > 0 && customer["active_card"] == nil {
+		return nil, cardError("missing", "card", "This customer has no attached card")
+	}
+	return sub, nil
+}
+
+// startSubscription attaches sub to customer, replacing any subscription it
+// had, and bills the customer for the first period unless sub is trialing.
+func (s *Server) startSubscription(customer, sub object) *apiError {
+	eventType := "customer.subscription.created"
+	if customer["subscription"] != nil {
+		eventType = "customer.subscription.updated"
+	}
+	customer["subscription"] = sub
+	s.record(eventType, sub)
+	if sub["status"] == "active" {
+		return s.bill(customer, sub)
+	}
+	return nil
+}
+
+// endSubscription cancels sub immediately.
+func (s *Server) endSubscription(customer, sub object) {
+	now := s.now()
+	sub["status"] = "canceled"
+	if sub["canceled_at"] == nil {
+		sub["canceled_at"] = now
+	}
+	sub["ended_at"] = now
+	customer["subscription"] = nil
+	s.record("customer.subscription.deleted", sub)
+}
+
+func (s *Server) subscribe(r *http.Request) (interface{}, *apiError) {
+	customer, ok := s.customers[r.PathValue("id")]
+	if !ok {
+		return nil, noSuch("customer", r.PathValue("id"))
+	}
+	planID := r.Form.Get("plan")
+	if planID == "" {
+		return nil, missingParam("plan")
+	}
+	card, apiErr := s.customerCard(r)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	if card != nil {
+		customer["active_card"] = card
+	}
+	sub, apiErr := s.newSubscription(r, customer, planID)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	if coupon := r.Form.Get("coupon"); coupon != "" {
+		if apiErr := s.applyCoupon(customer, coupon); apiErr != nil {
+			return nil, apiErr
+		}
+	}
+	if apiErr := s.startSubscription(customer, sub); apiErr != nil {
+		return nil, apiErr
+	}
+	return sub, nil
+}
+
+func (s *Server) unsubscribe(r *http.Request) (interface{}, *apiError) {
+	id := r.PathValue("id")
+	customer, ok := s.customers[id]
+	if !ok {
+		return nil, noSuch("customer", id)
+	}
+	sub, ok := customer["subscription"].(object)
+	if !ok {
+		return nil, &apiError{Status: 404, Type: "invalid_request_error", Message: "No active subscriptions for customer: " + id}
+	}
+	if r.Form.Get("at_period_end") == "true" {
+		sub["cancel_at_period_end"] = true
+		sub["canceled_at"] = s.now()
+		s.record("customer.subscription.updated", sub)
+		return sub, nil
+	}
+	s.endSubscription(customer, sub)
+	return sub, nil
+}
@@ -0,0 +1,92 @@
+package stripetest
+
+// Card numbers that the Server, like Stripe in test mode, treats specially.
+// Any other number that passes the Luhn check is charged successfully.
+const (
+	// CardSuccess is charged successfully.
+	CardSuccess = "4242424242424242"
+	// CardIncorrectNumber fails the Luhn check, with an incorrect_number error.
+	CardIncorrectNumber = "4242424242424241"
+	// CardDeclined is declined with a card_declined error.
+	CardDeclined = "4000000000000002"
+	// CardInsufficientFunds is declined with a card_declined error, and a
+	// decline code of insufficient_funds.
+	CardInsufficientFunds = "4000000000009995"
+	// CardLost is declined with a card_declined error, and a decline code of
+	// lost_card.
+	CardLost = "4000000000009987"
+	// CardStolen is declined with a card_declined error, and a decline code of
+	// stolen_card.
+	CardStolen = "4000000000009979"
+	// CardIncorrectCVC is declined with an incorrect_cvc error.
+	CardIncorrectCVC = "4000000000000127"
+	// CardExpired is declined with an expired_card error.
+	CardExpired = "4000000000000069"
+	// CardProcessingError is declined with a processing_error error.
+	CardProcessingError = "4000000000000119"
+	// CardDeclinedAfterAttaching can be attached to a customer, but charges to
+	// it are declined with a card_declined error.
+	CardDeclinedAfterAttaching = "4000000000000341"
+	// CardCVCCheckFails is charged successfully, but its cvc_check is "fail"
+	// when a CVC is given.
+	CardCVCCheckFails = "4000000000000101"
+	// CardAddressLine1CheckFails is charged successfully, but its
+	// address_line1_check is "fail".
+	CardAddressLine1CheckFails = "4000000000000028"
+	// CardZipCheckFails is charged successfully, but its address_zip_check is
+	// "fail".
+	CardZipCheckFails = "4000000000000036"
+	// CardChecksUnavailable is charged successfully, but its
+	// address_line1_check and address_zip_check are "unavailable".
+	CardChecksUnavailable = "4000000000000044"
+)
+
+// decline is how a charge to a test card number fails.
+type decline struct {
+	code, declineCode, param, message string
+}
+
+var declines = map[string]decline{
+	CardDeclined:               {"card_declined", "generic_decline", "", "Your card was declined."},
+	CardInsufficientFunds:      {"card_declined", "insufficient_funds", "", "Your card has insufficient funds."},
+	CardLost:                   {"card_declined", "lost_card", "", "Your card was declined."},
+	CardStolen:                 {"card_declined", "stolen_card", "", "Your card was declined."},
+	CardIncorrectCVC:           {"incorrect_cvc", "", "cvc", "Your card's security code is incorrect."},
+	CardExpired:                {"expired_card", "", "exp_month", "Your card has expired."},
+	CardProcessingError:        {"processing_error", "", "", "An error occurred while processing your card. Try again in a little bit."},
+	CardDeclinedAfterAttaching: {"card_declined", "generic_decline", "", "Your card was declined."},
+}
+
+// checks returns the results of the CVC, address line 1, and zip code checks
+// for a card with number, given which of those were provided.
+func checks(number string, cvc, line1, zip bool) (cvcCheck, line1Check, zipCheck interface{}) {
+	result := func(provided bool, fail bool) interface{} {
+		switch {
+		case !provided:
+			return nil
+		case number == CardChecksUnavailable:
+			return "unavailable"
+		case fail:
+			return "fail"
+		}
+		return "pass"
+	}
+	cvcCheck = result(cvc, number == CardCVCCheckFails)
+	if cvc && number == CardChecksUnavailable {
+		cvcCheck = "pass"
+	}
+	return cvcCheck, result(line1, number == CardAddressLine1CheckFails), result(zip, number == CardZipCheckFails)
+}
+
+// declined returns the error for attaching card to a customer, or for charging
+// it if charging is true, or nil if that succeeds.
+func (s *Server) declined(card object, charging bool) *apiError {
+	number := s.cardNumbers[card["id"].(string)]
+	d, ok := declines[number]
+	if !ok || !charging && number == CardDeclinedAfterAttaching {
+		return nil
+	}
+	apiErr := cardError(d.code, d.param, d.message)
+	apiErr.DeclineCode = d.declineCode
+	return apiErr
+}
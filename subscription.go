@@ -1,44 +1,59 @@
 package stripe
 
 import (
+	"context"
 	"encoding/json"
 	"net/url"
-	"strconv"
 )
 
 type Subscription struct {
-	Status            string    `json:"status"` // "trialing"/"active"/"past_due"/"canceled"/"unpaid"
-	Object            string    `json:"object"` // Should always be "subscription"
-	PeriodStart       int64     `json:"current_period_start"`
-	PeriodEnd         int64     `json:"current_period_end"`
-	CancelAtPeriodEnd bool      `json:"cancel_at_period_end"`
-	CanceledAt        int64     `json:"canceled_at"`
-	EndedAt           int64     `json:"ended_at"`
-	Start             int64     `json:"start"`
-	TrialStart        int64     `json:"trial_start"`
-	TrialEnd          int64     `json:"trial_end"`
-	Plan              *Plan     `json:"plan"`
-	CustomerID          string    `json:"customer"` // The Customer's ID
-	Error             *RawError `json:"error"`
+	Status            string            `json:"status"` // "trialing"/"active"/"past_due"/"canceled"/"unpaid"
+	Object            string            `json:"object"` // Should always be "subscription"
+	PeriodStart       int64             `json:"current_period_start"`
+	PeriodEnd         int64             `json:"current_period_end"`
+	CancelAtPeriodEnd bool              `json:"cancel_at_period_end"`
+	CanceledAt        int64             `json:"canceled_at"`
+	EndedAt           int64             `json:"ended_at"`
+	Start             int64             `json:"start"`
+	TrialStart        int64             `json:"trial_start"`
+	TrialEnd          int64             `json:"trial_end" form:"trial_end,omitempty"`
+	Plan              *Plan             `json:"plan"`
+	CustomerID        string            `json:"customer"` // The Customer's ID
+	Metadata          map[string]string `json:"metadata" form:"metadata,omitempty"`
+	Error             *RawError         `json:"error"`
+	rawObject
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (subscription *Subscription) UnmarshalJSON(data []byte) error {
+	type plainSubscription Subscription
+	return decodeRaw(data, (*plainSubscription)(subscription), &subscription.rawObject)
+}
+
+// MarshalJSON implements json.Marshaler.
+func (subscription Subscription) MarshalJSON() ([]byte, error) {
+	type plainSubscription Subscription
+	return encodeRaw(plainSubscription(subscription), subscription.rawObject)
 }
 
 // Values assigns the applicable properties of *subscription to the appropriate keys
 // in *values. This makes constructing an HTTP request around a Subscription simpler.
 func (subscription *Subscription) Values(values *url.Values) error {
 	if subscription == nil {
-		// TODO: throw an error
+		return invalid("subscription", "no subscription was provided")
 	}
-	if subscription.Plan == nil {
-		// TODO: throw an error
+	if subscription.Plan == nil || subscription.Plan.ID == "" {
+		return invalid("plan", "the subscription has no plan ID")
 	}
-	if subscription.Plan.ID == "" {
-		// TODO: throw an error
+	if subscription.TrialEnd < 0 {
+		return invalid("trial_end", "must not be negative")
 	}
-	values.Set("plan", subscription.Plan.ID)
-	if subscription.TrialEnd > 0 {
-		values.Set("trial_end", strconv.FormatInt(subscription.TrialEnd, 10))
+	err := validMetadata(subscription.Metadata)
+	if err != nil {
+		return err
 	}
-        return nil
+	values.Set("plan", subscription.Plan.ID)
+	return encodeForm("", subscription, values)
 }
 
 // Subscribe updates the customer's plan. The customer will be billed monthly according to the new plan.
@@ -47,13 +62,18 @@ func (subscription *Subscription) Values(values *url.Values) error {
 //
 // If couponID is non-empty, it will be used as the ID of a coupon to apply to the customer.
 //
-// If prorate is true, the customer will be prorated to make up for the price changes
+// If prorate is true, the customer will be prorated to make up for the price changes.
 //
 // If chargeable is non-nil, it will be attached to the customer. Can be either a token or a credit card.
 func (stripe *Stripe) Subscribe(subscription *Subscription, couponID string, prorate bool, chargeable Chargeable) (resp *Subscription, err error) {
+	return stripe.SubscribeCtx(context.Background(), subscription, couponID, prorate, chargeable)
+}
+
+// SubscribeCtx is like Subscribe, but uses ctx for the request to Stripe.
+func (stripe *Stripe) SubscribeCtx(ctx context.Context, subscription *Subscription, couponID string, prorate bool, chargeable Chargeable) (resp *Subscription, err error) {
 	values := make(url.Values)
-	if subscription.CustomerID == "" {
-		// TODO: throw an error
+	if subscription == nil || subscription.CustomerID == "" {
+		return nil, invalid("customer", "the subscription has no customer ID")
 	}
 	err = subscription.Values(&values)
 	if err != nil {
@@ -66,10 +86,13 @@ func (stripe *Stripe) Subscribe(subscription *Subscription, couponID string, pro
 		values.Set("prorate", "false")
 	}
 	if chargeable != nil {
-		chargeable.ChargeValues(&values)
+		err = chargeable.ChargeValues(&values)
+		if err != nil {
+			return nil, err
+		}
 	}
 	data := values.Encode()
-	r, err := stripe.request("POST", "customers/"+subscription.CustomerID+"/subscription", data)
+	r, err := stripe.request(ctx, "POST", "customers/"+subscription.CustomerID+"/subscription", data)
 	if err != nil {
 		return nil, err
 	}
@@ -78,7 +101,7 @@ func (stripe *Stripe) Subscribe(subscription *Subscription, couponID string, pro
 		return nil, err
 	}
 	if resp.Error != nil {
-		// TODO: Throw an error
+		return nil, resp.Error.typed(200)
 	}
 	return
 }
@@ -90,6 +113,11 @@ func (stripe *Stripe) Subscribe(subscription *Subscription, couponID string, pro
 //
 // Any pending invoice items will still be charged for at the end of the period unless they are manually deleted.
 func (stripe *Stripe) Unsubscribe(customerID string, at_period_end bool) (resp *Subscription, err error) {
+	return stripe.UnsubscribeCtx(context.Background(), customerID, at_period_end)
+}
+
+// UnsubscribeCtx is like Unsubscribe, but uses ctx for the request to Stripe.
+func (stripe *Stripe) UnsubscribeCtx(ctx context.Context, customerID string, at_period_end bool) (resp *Subscription, err error) {
 	values := make(url.Values)
 	if at_period_end {
 		values.Set("at_period_end", "true")
@@ -98,7 +126,7 @@ func (stripe *Stripe) Unsubscribe(customerID string, at_period_end bool) (resp *
 	if params != "" {
 		params = "?" + params
 	}
-	r, err := stripe.request("DELETE", "customers/"+customerID+"/subscription"+params, "")
+	r, err := stripe.request(ctx, "DELETE", "customers/"+customerID+"/subscription"+params, "")
 	if err != nil {
 		return nil, err
 	}
@@ -107,7 +135,7 @@ func (stripe *Stripe) Unsubscribe(customerID string, at_period_end bool) (resp *
 		return nil, err
 	}
 	if resp.Error != nil {
-		// TODO: Throw an error
+		return nil, resp.Error.typed(200)
 	}
 	return
 }
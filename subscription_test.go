@@ -1,4 +1,83 @@
 package stripe
 
-//TODO: TestSubscribe
-//TODO: TestUnsubscribe
+import (
+	"errors"
+	"testing"
+)
+
+func TestSubscribe(t *testing.T) {
+	API, _ := emulate(t)
+	if _, err := API.CreatePlan(GOLD); err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	customer, err := API.CreateCustomer(&Customer{Email: "oso@example.com"}, nil, "", "", -1)
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	sub, err := API.Subscribe(&Subscription{CustomerID: customer.ID, Plan: &Plan{ID: GOLD.ID}}, "", true, nil)
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	if sub.Status != "trialing" {
+		t.Errorf("Status is %v, expected %v", sub.Status, "trialing")
+	}
+	if sub.TrialEnd-sub.TrialStart != 14*24*60*60 {
+		t.Errorf("trial lasts %v seconds, expected 14 days", sub.TrialEnd-sub.TrialStart)
+	}
+	customer, err = API.GetCustomer(customer.ID)
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	if customer.Subscription == nil || customer.Subscription.Plan.ID != GOLD.ID {
+		t.Errorf("Subscription is %v, expected one to %v", customer.Subscription, GOLD.ID)
+	}
+}
+
+func TestSubscribeWithoutCard(t *testing.T) {
+	API, _ := emulate(t)
+	if _, err := API.CreatePlan(&Plan{ID: "basic", Name: "Basic", Amount: 500, Currency: "usd", Interval: "month"}); err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	customer, err := API.CreateCustomer(&Customer{}, nil, "", "", -1)
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	_, err = API.Subscribe(&Subscription{CustomerID: customer.ID, Plan: &Plan{ID: "basic"}}, "", true, nil)
+	var cardErr *CardError
+	if !errors.As(err, &cardErr) {
+		t.Fatalf("err = %v, want a *CardError", err)
+	}
+	if cardErr.Code != "missing" {
+		t.Errorf("Code is %v, expected %v", cardErr.Code, "missing")
+	}
+}
+
+func TestUnsubscribe(t *testing.T) {
+	API, _ := emulate(t)
+	if _, err := API.CreatePlan(GOLD); err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	customer, err := API.CreateCustomer(&Customer{}, VALID, GOLD.ID, "", -1)
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	sub, err := API.Unsubscribe(customer.ID, true)
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	if !sub.CancelAtPeriodEnd || sub.Status == "canceled" {
+		t.Errorf("subscription is %+v, expected it to remain until the end of the period", sub)
+	}
+	sub, err = API.Unsubscribe(customer.ID, false)
+	if err != nil {
+		t.Fatalf("err = %v, want %v", err, nil)
+	}
+	if sub.Status != "canceled" {
+		t.Errorf("Status is %v, expected %v", sub.Status, "canceled")
+	}
+	_, err = API.Unsubscribe(customer.ID, false)
+	var notFound *NotFoundError
+	if !errors.As(err, &notFound) {
+		t.Errorf("err = %v, want a *NotFoundError", err)
+	}
+}
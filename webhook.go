@@ -0,0 +1,80 @@
+package stripe
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+)
+
+// DefaultMaxWebhookBytes is the largest webhook body a WebhookHandler accepts
+// unless told otherwise.
+const DefaultMaxWebhookBytes = 1 << 20
+
+// WebhookHandler is an http.Handler that receives Events POSTed by Stripe's
+// webhooks and passes them to a Dispatcher.
+//
+// It responds 200 once the Event's handlers succeed (or if it has none), 400 if
+// the request isn't a well-formed, authentic Event, 405 if it isn't a POST, and
+// 500 if a handler returns an error, which makes Stripe deliver the Event again
+// later. If the Verifier can't reach a decision, it responds 503, and if the
+// Event is being handled by another delivery, 409.
+type WebhookHandler struct {
+	Dispatcher *Dispatcher
+	// Verifier authenticates each webhook before it is dispatched. Without one,
+	// anyone able to reach the handler can forge Events; set it to a
+	// *SignatureVerifier or *RefetchVerifier in production.
+	Verifier EventVerifier
+	// MaxBodyBytes limits the size of the request body. DefaultMaxWebhookBytes
+	// is used if it is zero.
+	MaxBodyBytes int64
+}
+
+// NewWebhookHandler returns a *WebhookHandler that passes Events to dispatcher.
+func NewWebhookHandler(dispatcher *Dispatcher) *WebhookHandler {
+	return &WebhookHandler{Dispatcher: dispatcher}
+}
+
+func (h *WebhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "Method not allowed.", http.StatusMethodNotAllowed)
+		return
+	}
+	limit := h.MaxBodyBytes
+	if limit <= 0 {
+		limit = DefaultMaxWebhookBytes
+	}
+	payload, err := io.ReadAll(http.MaxBytesReader(w, r.Body, limit))
+	if err != nil {
+		http.Error(w, "Could not read request body.", http.StatusBadRequest)
+		return
+	}
+	var event *Event
+	if h.Verifier != nil {
+		event, err = h.Verifier.VerifyEvent(r, payload)
+	} else {
+		err = json.Unmarshal(payload, &event)
+	}
+	var unavailable *UnavailableError
+	if errors.As(err, &unavailable) {
+		http.Error(w, "Could not verify event.", http.StatusServiceUnavailable)
+		return
+	}
+	if err != nil || event == nil || event.ID == "" || event.Type == "" {
+		http.Error(w, "Request body is not a valid event.", http.StatusBadRequest)
+		return
+	}
+	if h.Dispatcher != nil {
+		err = h.Dispatcher.Dispatch(r.Context(), event)
+		if err == ErrEventInProgress {
+			http.Error(w, "Event is already being handled.", http.StatusConflict)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Could not handle event.", http.StatusInternalServerError)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+}
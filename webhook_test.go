@@ -0,0 +1,121 @@
+package stripe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const chargeSucceeded = `{
+	"id": "evt_123",
+	"object": "event",
+	"type": "charge.succeeded",
+	"created": 1360000000,
+	"livemode": false,
+	"pending_webhooks": 1,
+	"data": {"object": {"id": "ch_123", "object": "charge", "amount": 500, "currency": "usd", "paid": true}}
+}`
+
+func postEvent(handler http.Handler, body string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("POST", "/webhook", strings.NewReader(body)))
+	return recorder
+}
+
+func TestWebhookDispatchesTypedObject(t *testing.T) {
+	dispatcher := NewDispatcher()
+	var got *Charge
+	dispatcher.HandleCharge("charge.succeeded", func(ctx context.Context, event *Event, charge *Charge) error {
+		got = charge
+		return nil
+	})
+	recorder := postEvent(NewWebhookHandler(dispatcher), chargeSucceeded)
+	if recorder.Code != 200 {
+		t.Fatalf("status is %v, expected %v", recorder.Code, 200)
+	}
+	if got == nil || got.ID != "ch_123" || got.Amount != 500 {
+		t.Errorf("charge is %+v, expected ch_123 for 500", got)
+	}
+}
+
+func TestTypedHandlerSkipsOtherObjects(t *testing.T) {
+	dispatcher := NewDispatcher()
+	var customers []string
+	dispatcher.HandleCustomer("customer.*", func(ctx context.Context, event *Event, customer *Customer) error {
+		customers = append(customers, customer.ID)
+		return nil
+	})
+	handler := NewWebhookHandler(dispatcher)
+	for _, body := range []string{
+		`{"id": "evt_1", "type": "customer.created", "data": {"object": {"id": "cus_123", "object": "customer"}}}`,
+		`{"id": "evt_2", "type": "customer.subscription.created", "data": {"object": {"id": "sub_123", "object": "subscription"}}}`,
+	} {
+		if recorder := postEvent(handler, body); recorder.Code != 200 {
+			t.Errorf("status is %v, expected %v for %s", recorder.Code, 200, body)
+		}
+	}
+	if len(customers) != 1 || customers[0] != "cus_123" {
+		t.Errorf("customers are %v, expected %v", customers, []string{"cus_123"})
+	}
+}
+
+func TestWebhookResponses(t *testing.T) {
+	dispatcher := NewDispatcher()
+	dispatcher.Handle("charge.succeeded", func(ctx context.Context, event *Event) error {
+		return errors.New("database unavailable")
+	})
+	dispatcher.HandleCustomer("charge.failed", func(ctx context.Context, event *Event, customer *Customer) error {
+		return nil
+	})
+	handler := NewWebhookHandler(dispatcher)
+
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"handler error", chargeSucceeded, 500},
+		{"unhandled type", strings.Replace(chargeSucceeded, "charge.succeeded", "charge.refunded", 1), 200},
+		{"other object type", strings.Replace(chargeSucceeded, "charge.succeeded", "charge.failed", 1), 200},
+		{"malformed", `{"id": `, 400},
+		{"no id", `{"type": "charge.succeeded"}`, 400},
+	}
+	for _, test := range tests {
+		if recorder := postEvent(handler, test.body); recorder.Code != test.want {
+			t.Errorf("%v: status is %v, expected %v", test.name, recorder.Code, test.want)
+		}
+	}
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/webhook", nil))
+	if recorder.Code != 405 {
+		t.Errorf("GET: status is %v, expected %v", recorder.Code, 405)
+	}
+}
+
+func TestEventObjectTypes(t *testing.T) {
+	tests := map[string]string{
+		"customer.created":              "*stripe.Customer",
+		"customer.subscription.deleted": "*stripe.Subscription",
+		"customer.discount.created":     "*stripe.Discount",
+		"invoice.payment_failed":        "*stripe.Invoice",
+		"invoiceitem.created":           "*stripe.InvoiceItem",
+		"plan.updated":                  "*stripe.Plan",
+		"coupon.deleted":                "*stripe.Coupon",
+		"transfer.paid":                 "map[string]interface {}",
+	}
+	for eventType, want := range tests {
+		object, err := decodeEventObject(eventType, []byte(`{"id": "obj_123"}`))
+		if err != nil {
+			t.Errorf("%v: err = %v, want %v", eventType, err, nil)
+			continue
+		}
+		if got := fmt.Sprintf("%T", object); got != want {
+			t.Errorf("%v: object is a %v, expected %v", eventType, got, want)
+		}
+	}
+}